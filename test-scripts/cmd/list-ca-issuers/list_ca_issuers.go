@@ -0,0 +1,1261 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// out receives the human-readable report. It is discarded with -json so
+// stdout carries only the JSON document.
+var out io.Writer = os.Stdout
+
+// errOut receives certificate parse errors. It is stdout alongside the
+// report, and stderr when the report is discarded for an alternate output.
+var errOut io.Writer = os.Stdout
+
+// verbose reports PEM blocks that were skipped or only partly used
+// (-verbose).
+var verbose bool
+
+// parseErrors counts certificates, and trailing data, that could not be
+// parsed.
+var parseErrors int
+
+func main() {
+	annotationsFile := flag.String("annotations", "", "YAML file mapping SHA-256 fingerprints to notes")
+	var bundles labeledBundles
+	flag.Var(&bundles, "bundle", "labeled bundle `name=path` (repeatable); tags each cert with the bundle it came from")
+	subsetOf := flag.String("subset-of", "", "fail unless every cert in the bundle is also in this reference `bundle`")
+	var forbidSubjects, requireSubjects regexpList
+	flag.Var(&forbidSubjects, "forbid-subject", "fail if any cert's Subject matches this `regex` (repeatable)")
+	flag.Var(&requireSubjects, "require-subject", "fail unless some cert's Subject matches this `regex` (repeatable)")
+	expectKey := flag.String("expect-key", "", "fail if any cert's key is not this `algorithm`: rsa, rsa-2048, rsa-3072, rsa-4096, ecdsa, ecdsa-p256, ecdsa-p384, ecdsa-p521 or ed25519")
+	maxCerts := flag.Int("max-certs", 0, "fail if the bundle contains more than `N` certificates")
+	diffAgainst := flag.String("diff", "", "compare the bundle against this baseline `bundle`, listing added, removed and common certificates")
+	failOnChange := flag.Bool("fail-on-change", false, "-diff: exit non-zero if any certificate was added or removed")
+	supersetOf := flag.String("superset-of", "", "fail unless the bundle contains every cert in this reference `bundle`")
+	warnDays := flag.Int("warn-days", 30, "warn about certificates expiring within this many `days`")
+	preflightMount := flag.Bool("preflight-mount", false, "check the bundle fits in a ConfigMap: total size against -mount-limit and a sane cert count")
+	mountLimit := flag.Int("mount-limit", configMapSizeLimit, "-preflight-mount: maximum ConfigMap size in `bytes`")
+	browsers := flag.String("browser", "", "comma-separated `browsers` (chrome, firefox, safari) whose root store must trust the bundle")
+	browserRoots := flag.String("browser-roots", "browser-roots", "`directory` holding each browser's published trust list as <browser>.pem")
+	jsonOutput := flag.Bool("json", false, "emit the certificates as a JSON array on stdout instead of the text report")
+	failOnWeak := flag.Bool("fail-on-weak", false, "exit non-zero if any certificate has a SHA-1 or MD5 signature or an RSA key under 2048 bits")
+	fingerprintOnly := flag.Bool("fingerprint-only", false, "print only each certificate's SHA-256 fingerprint, one per line, for quick diffing")
+	dedup := flag.Bool("dedup", false, "emit the bundle as PEM on stdout with duplicate certificates removed, instead of the text report")
+	flag.BoolVar(&verbose, "verbose", false, "note PEM blocks that are not certificates and were skipped, such as keys or CRLs in trust store exports")
+	countOnly := flag.Bool("count-only", false, "print only the number of valid certificates; exit non-zero if there are none or any failed to parse")
+	table := flag.Bool("table", false, "list the certificates as a table, one row each, instead of a block per certificate")
+	reorder := flag.Bool("reorder", false, "emit the bundle as PEM on stdout in chain order, each certificate followed by its issuer, instead of the text report")
+	serial := flag.String("serial", "", "only list certificates with this serial `number`, as colon-separated hex or decimal; exit non-zero if none match")
+	flag.Usage = func() {
+		fmt.Println("Usage: go run ./cmd/list-ca-issuers [flags] <ca-bundle-file>")
+		fmt.Println("       go run ./cmd/list-ca-issuers [flags] -bundle name=path [-bundle name=path ...]")
+		fmt.Println("       Use - as a bundle path to read it from stdin")
+		fmt.Println("Example: go run ./cmd/list-ca-issuers /tmp/ca.crt")
+		fmt.Println("         oc extract -n openshift-config configmap/user-ca-bundle --to=- | go run ./cmd/list-ca-issuers -")
+		fmt.Println("         go run ./cmd/list-ca-issuers -annotations notes.yaml /tmp/ca.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -bundle cluster=/tmp/ca.crt -bundle partner=/tmp/partner.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -browser chrome,firefox,safari -browser-roots ./browser-roots /tmp/ca.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -table /tmp/ca.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -serial 03:A1:5F:0C:7E /tmp/ca.crt")
+		fmt.Println("         test \"$(go run ./cmd/list-ca-issuers -count-only /tmp/ca.crt)\" -ge 2")
+		fmt.Println("         go run ./cmd/list-ca-issuers -json /tmp/ca.crt | jq '.[] | select(.letsEncrypt)'")
+		fmt.Println("         go run ./cmd/list-ca-issuers -dedup /tmp/ca.crt > /tmp/ca-dedup.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -reorder /tmp/chain.crt > /tmp/chain-ordered.crt")
+		fmt.Println("         go run ./cmd/list-ca-issuers -diff /tmp/ca-old.crt -fail-on-change /tmp/ca-new.crt")
+		fmt.Println()
+		fmt.Println("Flags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 && len(bundles) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	alternateOutputs := 0
+	for _, set := range []bool{*jsonOutput, *dedup, *fingerprintOnly, *reorder, *countOnly} {
+		if set {
+			alternateOutputs++
+		}
+	}
+	if alternateOutputs > 1 {
+		fmt.Fprintln(os.Stderr, "Error: -json, -dedup, -fingerprint-only, -reorder and -count-only are mutually exclusive")
+		os.Exit(1)
+	}
+	if *table && alternateOutputs > 0 {
+		fmt.Fprintln(os.Stderr, "Error: -table cannot be combined with -json, -dedup, -fingerprint-only, -reorder or -count-only")
+		os.Exit(1)
+	}
+	if alternateOutputs > 0 {
+		out = io.Discard
+		errOut = os.Stderr
+	}
+
+	var serials []*big.Int
+	if *serial != "" {
+		var err error
+		serials, err = parseSerial(*serial)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -serial: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load fingerprint -> note annotations, if any
+	annotations := map[string]string{}
+	if *annotationsFile != "" {
+		var err error
+		annotations, err = loadAnnotations(*annotationsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading annotations: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// A plain positional bundle is treated as an unlabeled source
+	if flag.NArg() > 0 {
+		bundles = append(labeledBundles{{path: flag.Arg(0)}}, bundles...)
+	}
+
+	// fingerprint -> every bundle containing that cert and how many times;
+	// only tracked when labeled bundles were given
+	var sources map[string][]sourceCount
+	var certs []*x509.Certificate
+	rawSize := 0
+
+	for _, b := range bundles {
+		// Read the CA bundle file
+		caData, err := readBundle(b.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+
+		rawSize += len(caData)
+		parsed := parseBundle(caData)
+		if len(bundles) == 1 && b.name == "" {
+			certs = parsed
+			continue
+		}
+
+		if sources == nil {
+			sources = map[string][]sourceCount{}
+		}
+		label := b.name
+		if label == "" {
+			label = b.path
+		}
+		for _, cert := range parsed {
+			if !recordSource(sources, certFingerprint(cert), label) {
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	// -serial narrows everything below, checks included, to the matches
+	if serials != nil {
+		certs = filterBySerial(certs, serials)
+		if len(certs) == 0 {
+			fmt.Fprintf(os.Stderr, "❌ No certificate with serial %s found\n", *serial)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintf(out, "=== Certificates in CA Bundle ===\n\n")
+
+	// With -table the blocks below are still computed, for the totals,
+	// but discarded in favour of one row per certificate
+	report := out
+	if *table {
+		writeTable(out, certs)
+		fmt.Fprintln(out)
+		out = io.Discard
+	}
+	
+	count := 0
+	multiSource, repeatedInSource := 0, 0
+	missingServerAuth := 0
+	weak := 0
+	expired, expiringSoon := 0, 0
+	now := time.Now()
+	for _, cert := range certs {
+		count++
+		fp := certFingerprint(cert)
+		if note, ok := annotations[fp]; ok {
+			fmt.Fprintf(out, "Certificate #%d: 📝 %s\n", count, note)
+		} else {
+			fmt.Fprintf(out, "Certificate #%d:\n", count)
+		}
+		fmt.Fprintf(out, "  Subject: %s\n", cert.Subject.String())
+		fmt.Fprintf(out, "  Issuer:  %s\n", cert.Issuer.String())
+		fmt.Fprintf(out, "  Serial:  %s\n", colonHex(cert.SerialNumber.Bytes()))
+		printSANs(cert)
+		sha256Sum := sha256.Sum256(cert.Raw)
+		sha1Sum := sha1.Sum(cert.Raw)
+		fmt.Fprintf(out, "  SHA-256: %s\n", colonHex(sha256Sum[:]))
+		fmt.Fprintf(out, "  SHA-1:   %s\n", colonHex(sha1Sum[:]))
+		fmt.Fprintf(out, "  Signature: %s\n", cert.SignatureAlgorithm)
+		fmt.Fprintf(out, "  Key:       %s\n", keyDescription(cert))
+		if problems := weakCrypto(cert); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintf(out, "  ⚠️  Weak crypto: %s\n", p)
+			}
+			weak++
+		}
+		fmt.Fprintf(out, "  Valid:   %s to %s\n", cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+		days := int(cert.NotAfter.Sub(now).Hours() / 24)
+		switch {
+		case now.After(cert.NotAfter):
+			fmt.Fprintf(out, "  ❌ EXPIRED %d days ago\n", -days)
+			expired++
+		case days < *warnDays:
+			fmt.Fprintf(out, "  ⚠️  Expires in %d days\n", days)
+			expiringSoon++
+		default:
+			fmt.Fprintf(out, "  Expires in %d days\n", days)
+		}
+		if sources != nil {
+			counts := sources[fp]
+			var labels, repeated []string
+			for _, c := range counts {
+				if c.n > 1 {
+					labels = append(labels, fmt.Sprintf("%s (x%d)", c.label, c.n))
+					repeated = append(repeated, c.label)
+				} else {
+					labels = append(labels, c.label)
+				}
+			}
+			fmt.Fprintf(out, "  Source:  %s\n", strings.Join(labels, ", "))
+			if len(counts) > 1 {
+				fmt.Fprintf(out, "  🔀 Present in %d sources\n", len(counts))
+				multiSource++
+			}
+			if len(repeated) > 0 {
+				fmt.Fprintf(out, "  🔁 Repeated within %s\n", strings.Join(repeated, ", "))
+				repeatedInSource++
+			}
+		}
+		
+		// Leaf certs must allow TLS server auth (or carry no EKU at all)
+		if !cert.IsCA && len(cert.ExtKeyUsage) > 0 {
+			fmt.Fprintf(out, "  EKU:     %s\n", strings.Join(extKeyUsageNames(cert.ExtKeyUsage), ", "))
+			if !allowsServerAuth(cert) {
+				fmt.Fprintf(out, "  ⚠️  Leaf certificate lacks ServerAuth EKU - TLS clients will reject it as a server cert\n")
+				missingServerAuth++
+			}
+		}
+
+		// Check for Let's Encrypt
+		if isLetsEncrypt(cert) {
+			fmt.Fprintf(out, "  ⭐ Let's Encrypt certificate detected!\n")
+		}
+		
+		fmt.Fprintln(out)
+	}
+	out = report
+	
+	fmt.Fprintf(out, "Total certificates: %d\n", count)
+	if sources != nil {
+		fmt.Fprintf(out, "Certificates present in more than one source: %d\n", multiSource)
+		fmt.Fprintf(out, "Certificates repeated within a single source: %d\n", repeatedInSource)
+	}
+	if missingServerAuth > 0 {
+		fmt.Fprintf(out, "Leaf certificates missing ServerAuth EKU: %d\n", missingServerAuth)
+	}
+	if weak > 0 {
+		fmt.Fprintf(out, "Certificates with weak crypto: %d\n", weak)
+	}
+	if expired > 0 {
+		fmt.Fprintf(out, "Expired certificates: %d\n", expired)
+	}
+	if expiringSoon > 0 {
+		fmt.Fprintf(out, "Certificates expiring within %d days: %d\n", *warnDays, expiringSoon)
+	}
+	reportDuplicates(certs)
+	reportChainOrder(certs)
+
+	relationshipOK := true
+	if *subsetOf != "" {
+		relationshipOK = checkSubset(certs, *subsetOf) && relationshipOK
+	}
+	if *supersetOf != "" {
+		relationshipOK = checkSuperset(certs, *supersetOf) && relationshipOK
+	}
+	diffOK := true
+	if *diffAgainst != "" {
+		changed := diffBundles(certs, *diffAgainst)
+		diffOK = !changed || !*failOnChange
+	}
+	subjectsOK := true
+	if len(forbidSubjects) > 0 || len(requireSubjects) > 0 {
+		subjectsOK = checkSubjectPolicy(certs, forbidSubjects, requireSubjects)
+	}
+
+	keysOK := true
+	if *expectKey != "" {
+		keysOK = checkKeyPolicy(certs, *expectKey)
+	}
+
+	sizeOK := true
+	if *maxCerts > 0 {
+		sizeOK = checkMaxCerts(certs, *maxCerts)
+	}
+
+	mountOK := true
+	if *preflightMount {
+		// Merged bundles would be mounted as their deduplicated union
+		size := rawSize
+		if sources != nil {
+			size = 0
+			for _, cert := range certs {
+				size += len(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+			}
+		}
+		mountOK = checkMountPreflight(size, len(certs), *mountLimit)
+	}
+
+	browsersOK := true
+	if *browsers != "" {
+		browsersOK = checkBrowserTrust(certs, strings.Split(*browsers, ","), *browserRoots)
+	}
+
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, certs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *fingerprintOnly {
+		for _, cert := range certs {
+			sum := sha256.Sum256(cert.Raw)
+			fmt.Println(colonHex(sum[:]))
+		}
+	}
+
+	if *dedup {
+		if err := writeDeduplicated(os.Stdout, certs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *countOnly {
+		fmt.Println(len(certs))
+	}
+
+	if *reorder {
+		for _, i := range chainOrder(certs) {
+			if err := pem.Encode(os.Stdout, &pem.Block{Type: "CERTIFICATE", Bytes: certs[i].Raw}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	weakOK := !*failOnWeak || weak == 0
+	countOK := !*countOnly || (len(certs) > 0 && parseErrors == 0)
+	if !relationshipOK || !subjectsOK || !keysOK || !sizeOK || !mountOK || !browsersOK || !weakOK || !diffOK || !countOK {
+		os.Exit(1)
+	}
+}
+
+// certJSON is the -json representation of a certificate.
+type certJSON struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	IsCA         bool      `json:"isCA"`
+	LetsEncrypt  bool      `json:"letsEncrypt"`
+}
+
+// writeJSON writes certs to w as an indented JSON array. The serial number
+// is rendered in hex, as openssl prints it.
+func writeJSON(w io.Writer, certs []*x509.Certificate) error {
+	entries := make([]certJSON, 0, len(certs))
+	for _, cert := range certs {
+		entries = append(entries, certJSON{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: strings.ToUpper(cert.SerialNumber.Text(16)),
+			NotBefore:    cert.NotBefore.UTC(),
+			NotAfter:     cert.NotAfter.UTC(),
+			IsCA:         cert.IsCA,
+			LetsEncrypt:  isLetsEncrypt(cert),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// isLetsEncrypt reports whether the certificate was issued by Let's Encrypt,
+// judged by the ISRG organization or one of its intermediate names.
+func isLetsEncrypt(cert *x509.Certificate) bool {
+	issuerStr := cert.Issuer.String()
+	return strings.Contains(issuerStr, "Let's Encrypt") ||
+		strings.Contains(issuerStr, "ISRG") ||
+		strings.Contains(cert.Issuer.CommonName, "R3") ||
+		strings.Contains(cert.Issuer.CommonName, "R10") ||
+		strings.Contains(cert.Issuer.CommonName, "R11") ||
+		strings.Contains(cert.Issuer.CommonName, "E1") ||
+		strings.Contains(cert.Issuer.CommonName, "E2")
+}
+
+// readBundle reads a CA bundle from path, or from stdin when path is "-",
+// so the tool can sit at the end of a pipe such as "oc extract ... --to=-".
+func readBundle(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// loadReferenceBundle reads a bundle to compare against and indexes it by
+// fingerprint.
+func loadReferenceBundle(path string) ([]*x509.Certificate, map[string]bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading reference bundle: %v\n", err)
+		os.Exit(1)
+	}
+	certs := parseBundle(data)
+	set := map[string]bool{}
+	for _, cert := range certs {
+		set[certFingerprint(cert)] = true
+	}
+	return certs, set
+}
+
+// checkSubset reports whether every cert in certs is present in the
+// reference bundle, listing the ones that are not.
+func checkSubset(certs []*x509.Certificate, refPath string) bool {
+	_, ref := loadReferenceBundle(refPath)
+	fmt.Fprintf(out, "\n=== Subset of %s ===\n", refPath)
+	ok := true
+	for _, cert := range certs {
+		if !ref[certFingerprint(cert)] {
+			fmt.Fprintf(out, "  ❌ Not in reference: %s\n", cert.Subject.String())
+			ok = false
+		}
+	}
+	if ok {
+		fmt.Fprintln(out, "  ✅ Bundle is a subset of the reference")
+	}
+	return ok
+}
+
+// checkSuperset reports whether every cert in the reference bundle is
+// present in certs, listing the ones that are missing.
+func checkSuperset(certs []*x509.Certificate, refPath string) bool {
+	refCerts, _ := loadReferenceBundle(refPath)
+	have := map[string]bool{}
+	for _, cert := range certs {
+		have[certFingerprint(cert)] = true
+	}
+	fmt.Fprintf(out, "\n=== Superset of %s ===\n", refPath)
+	ok := true
+	for _, cert := range refCerts {
+		if !have[certFingerprint(cert)] {
+			fmt.Fprintf(out, "  ❌ Missing from bundle: %s\n", cert.Subject.String())
+			ok = false
+		}
+	}
+	if ok {
+		fmt.Fprintln(out, "  ✅ Bundle is a superset of the reference")
+	}
+	return ok
+}
+
+// diffBundles compares certs against a baseline bundle by fingerprint and
+// lists the certificates added, removed and common to both. It reports
+// whether anything changed.
+func diffBundles(certs []*x509.Certificate, basePath string) bool {
+	baseCerts, base := loadReferenceBundle(basePath)
+	current := map[string]bool{}
+	for _, cert := range certs {
+		current[certFingerprint(cert)] = true
+	}
+
+	var added, removed, common []*x509.Certificate
+	for _, cert := range certs {
+		if base[certFingerprint(cert)] {
+			common = append(common, cert)
+		} else {
+			added = append(added, cert)
+		}
+	}
+	for _, cert := range baseCerts {
+		if !current[certFingerprint(cert)] {
+			removed = append(removed, cert)
+		}
+	}
+
+	fmt.Fprintf(out, "\n=== Diff against %s ===\n", basePath)
+	for _, cert := range added {
+		fmt.Fprintf(out, "  + %s\n", displayName(cert))
+	}
+	for _, cert := range removed {
+		fmt.Fprintf(out, "  - %s\n", displayName(cert))
+	}
+	for _, cert := range common {
+		fmt.Fprintf(out, "    %s\n", displayName(cert))
+	}
+	fmt.Fprintf(out, "  Added: %d, removed: %d, unchanged: %d\n", len(added), len(removed), len(common))
+	return len(added) > 0 || len(removed) > 0
+}
+
+// writeTable writes one aligned row per certificate, for scanning large
+// bundles that the per-certificate blocks make hard to take in.
+func writeTable(w io.Writer, certs []*x509.Certificate) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSUBJECT CN\tISSUER CN\tNOT AFTER\tCA")
+	for i, cert := range certs {
+		issuer := cert.Issuer.CommonName
+		if issuer == "" {
+			issuer = cert.Issuer.String()
+		}
+		isCA := ""
+		if cert.IsCA {
+			isCA = "yes"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, displayName(cert), issuer, cert.NotAfter.Format("2006-01-02"), isCA)
+	}
+	tw.Flush()
+}
+
+// parseSerial parses a -serial value. Colon-separated values, 0x-prefixed
+// values and values with hex letters are hex. Plain digits are ambiguous,
+// since openssl prints serials as bare hex, so both readings are returned.
+func parseSerial(value string) ([]*big.Int, error) {
+	s := strings.TrimSpace(value)
+	hexOnly := strings.Contains(s, ":")
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		s, hexOnly = rest, true
+	}
+	s = strings.ReplaceAll(s, ":", "")
+
+	var serials []*big.Int
+	if n, ok := new(big.Int).SetString(s, 16); ok && n.Sign() >= 0 {
+		serials = append(serials, n)
+	}
+	if !hexOnly {
+		if n, ok := new(big.Int).SetString(s, 10); ok && n.Sign() >= 0 {
+			serials = append(serials, n)
+		}
+	}
+	if len(serials) == 0 {
+		return nil, fmt.Errorf("%q is not a hex or decimal serial number", value)
+	}
+	return serials, nil
+}
+
+// filterBySerial returns the certificates whose serial number is one of
+// serials, in bundle order.
+func filterBySerial(certs []*x509.Certificate, serials []*big.Int) []*x509.Certificate {
+	var matches []*x509.Certificate
+	for _, cert := range certs {
+		for _, serial := range serials {
+			if cert.SerialNumber.Cmp(serial) == 0 {
+				matches = append(matches, cert)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// displayName is a short name for a certificate: its Subject CN, or the
+// full Subject when it has no CN.
+func displayName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// regexpList implements flag.Value for repeatable regular expression flags.
+type regexpList []*regexp.Regexp
+
+func (r *regexpList) String() string {
+	var parts []string
+	for _, re := range *r {
+		parts = append(parts, re.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *regexpList) Set(value string) error {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, re)
+	return nil
+}
+
+// checkSubjectPolicy enforces Subject DN naming rules: no cert may match a
+// forbidden pattern, and every required pattern must match at least one cert.
+func checkSubjectPolicy(certs []*x509.Certificate, forbid, require regexpList) bool {
+	fmt.Fprintln(out, "\n=== Subject Policy ===")
+	ok := true
+	for i, cert := range certs {
+		subject := cert.Subject.String()
+		for _, re := range forbid {
+			if re.MatchString(subject) {
+				fmt.Fprintf(out, "  ❌ Certificate #%d matches forbidden pattern %q: %s\n", i+1, re.String(), subject)
+				ok = false
+			}
+		}
+	}
+	for _, re := range require {
+		found := false
+		for _, cert := range certs {
+			if re.MatchString(cert.Subject.String()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(out, "  ❌ No certificate matches required pattern %q\n", re.String())
+			ok = false
+		}
+	}
+	if ok {
+		fmt.Fprintln(out, "  ✅ All certificates satisfy the Subject policy")
+	}
+	return ok
+}
+
+// printSANs prints the certificate's subject alternative names, one line
+// per kind, so a leaf pasted into a bundle shows which hosts it covers.
+func printSANs(cert *x509.Certificate) {
+	if len(cert.DNSNames) > 0 {
+		fmt.Fprintf(out, "  DNS:     %s\n", strings.Join(cert.DNSNames, ", "))
+	}
+	if len(cert.IPAddresses) > 0 {
+		ips := make([]string, len(cert.IPAddresses))
+		for i, ip := range cert.IPAddresses {
+			ips[i] = ip.String()
+		}
+		fmt.Fprintf(out, "  IP:      %s\n", strings.Join(ips, ", "))
+	}
+	if len(cert.URIs) > 0 {
+		uris := make([]string, len(cert.URIs))
+		for i, uri := range cert.URIs {
+			uris[i] = uri.String()
+		}
+		fmt.Fprintf(out, "  URI:     %s\n", strings.Join(uris, ", "))
+	}
+	if len(cert.EmailAddresses) > 0 {
+		fmt.Fprintf(out, "  Email:   %s\n", strings.Join(cert.EmailAddresses, ", "))
+	}
+}
+
+// keyAlgorithm describes a certificate's public key as a lowercase algorithm
+// name plus its size or curve, e.g. ("rsa", "2048") or ("ecdsa", "p384").
+func keyAlgorithm(cert *x509.Certificate) (string, string) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "rsa", fmt.Sprintf("%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ecdsa", strings.ToLower(strings.ReplaceAll(key.Curve.Params().Name, "-", ""))
+	case ed25519.PublicKey:
+		return "ed25519", ""
+	}
+	return strings.ToLower(cert.PublicKeyAlgorithm.String()), ""
+}
+
+// keyDescription describes a certificate's public key for display, e.g.
+// "RSA 2048 bits" or "ECDSA P-384".
+func keyDescription(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d bits", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// weakCrypto lists the reasons a certificate's signature or key is too
+// weak for a security review: MD5 or SHA-1 signatures and RSA keys under
+// 2048 bits.
+func weakCrypto(cert *x509.Certificate) []string {
+	var problems []string
+	switch cert.SignatureAlgorithm {
+	case x509.MD5WithRSA, x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+		problems = append(problems, fmt.Sprintf("signed with %s", cert.SignatureAlgorithm))
+	}
+	if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+		problems = append(problems, fmt.Sprintf("RSA key is only %d bits (minimum 2048)", key.N.BitLen()))
+	}
+	return problems
+}
+
+// checkKeyPolicy reports every cert whose key algorithm (and size or curve,
+// if given) differs from expected, e.g. "ecdsa-p384".
+func checkKeyPolicy(certs []*x509.Certificate, expected string) bool {
+	wantAlg, wantParam, _ := strings.Cut(strings.ToLower(expected), "-")
+	fmt.Fprintf(out, "\n=== Key Algorithm Policy: %s ===\n", expected)
+	ok := true
+	for i, cert := range certs {
+		alg, param := keyAlgorithm(cert)
+		if alg == wantAlg && (wantParam == "" || param == wantParam) {
+			continue
+		}
+		actual := alg
+		if param != "" {
+			actual += "-" + param
+		}
+		fmt.Fprintf(out, "  ❌ Certificate #%d uses %s: %s\n", i+1, actual, cert.Subject.String())
+		ok = false
+	}
+	if ok {
+		fmt.Fprintf(out, "  ✅ All %d certificates use %s\n", len(certs), expected)
+	}
+	return ok
+}
+
+const (
+	// configMapSizeLimit is the 1 MiB cap etcd places on a ConfigMap.
+	configMapSizeLimit = 1 << 20
+
+	// configMapOverhead approximates the serialized size of a ConfigMap
+	// besides its data: metadata, managed fields and the data key.
+	configMapOverhead = 4 << 10
+
+	// mountCertWarn is the cert count above which a bundle is suspicious;
+	// full public root stores hold under 200.
+	mountCertWarn = 500
+)
+
+// checkMountPreflight reports whether a bundle of the given size and cert
+// count can be safely mounted from a ConfigMap, warning at 80% of limit.
+func checkMountPreflight(size, count, limit int) bool {
+	projected := size + configMapOverhead
+	fmt.Fprintln(out, "\n=== ConfigMap Mount Preflight ===")
+	fmt.Fprintf(out, "  Bundle size:              %d bytes\n", size)
+	fmt.Fprintf(out, "  Projected ConfigMap size: ~%d bytes (%.1f%% of %d byte limit)\n", projected, 100*float64(projected)/float64(limit), limit)
+	fmt.Fprintf(out, "  Certificates:             %d\n", count)
+
+	ok := true
+	switch {
+	case projected > limit:
+		fmt.Fprintln(out, "  ❌ Bundle is too large for a ConfigMap; the apply will be rejected")
+		ok = false
+	case projected > limit*8/10:
+		fmt.Fprintln(out, "  ⚠️  Bundle is close to the ConfigMap size limit")
+	}
+	if count > mountCertWarn {
+		fmt.Fprintf(out, "  ⚠️  %d certificates is unusually many for a trust bundle (more than %d)\n", count, mountCertWarn)
+	}
+	if ok {
+		fmt.Fprintln(out, "  ✅ Bundle fits in a ConfigMap")
+	}
+	return ok
+}
+
+// browserTrustLists are the browsers -browser accepts. Their root stores are
+// not shipped with this tool; export them from the vendors' published lists
+// (Chrome Root Store, Mozilla certdata, Apple's trusted root list) as PEM.
+var browserTrustLists = map[string]bool{"chrome": true, "firefox": true, "safari": true}
+
+// bundleAnchors returns the certs at the top of each chain in the bundle:
+// self-signed roots, and certs whose issuer is not in the bundle. These are
+// what a browser must have in its own store for the bundle to be trusted.
+func bundleAnchors(certs []*x509.Certificate) []*x509.Certificate {
+	var anchors []*x509.Certificate
+	for _, cert := range certs {
+		selfSigned := bytes.Equal(cert.RawSubject, cert.RawIssuer)
+		hasIssuer := false
+		for _, other := range certs {
+			if other != cert && bytes.Equal(other.RawSubject, cert.RawIssuer) {
+				hasIssuer = true
+				break
+			}
+		}
+		if selfSigned || !hasIssuer {
+			anchors = append(anchors, cert)
+		}
+	}
+	return anchors
+}
+
+// checkBrowserTrust reports, per browser, whether each chain in the bundle
+// ends at a root in that browser's trust list: either the anchor itself is
+// in the list, or it is signed by a root that is.
+func checkBrowserTrust(certs []*x509.Certificate, browsers []string, rootsDir string) bool {
+	anchors := bundleAnchors(certs)
+	ok := true
+	for _, browser := range browsers {
+		browser = strings.ToLower(strings.TrimSpace(browser))
+		fmt.Fprintf(out, "\n=== Browser Trust: %s ===\n", browser)
+		if !browserTrustLists[browser] {
+			fmt.Fprintf(out, "  ❌ Unknown browser %q (expected chrome, firefox or safari)\n", browser)
+			ok = false
+			continue
+		}
+		path := filepath.Join(rootsDir, browser+".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(out, "  ❌ Cannot read %s trust list: %v\n", browser, err)
+			ok = false
+			continue
+		}
+		roots := parseBundle(data)
+		trusted := map[string]bool{}
+		for _, root := range roots {
+			trusted[certFingerprint(root)] = true
+		}
+		fmt.Fprintf(out, "  Trust list: %s (%d roots)\n", path, len(roots))
+
+		for _, anchor := range anchors {
+			if trusted[certFingerprint(anchor)] {
+				fmt.Fprintf(out, "  ✅ Trusted root: %s\n", anchor.Subject.String())
+				continue
+			}
+			var via *x509.Certificate
+			for _, root := range roots {
+				if bytes.Equal(root.RawSubject, anchor.RawIssuer) && anchor.CheckSignatureFrom(root) == nil {
+					via = root
+					break
+				}
+			}
+			if via != nil {
+				fmt.Fprintf(out, "  ✅ Chains to trusted root: %s\n", anchor.Subject.String())
+				fmt.Fprintf(out, "     via %s\n", via.Subject.String())
+			} else {
+				fmt.Fprintf(out, "  ❌ Not trusted by %s: %s\n", browser, anchor.Subject.String())
+				fmt.Fprintf(out, "     → %s users will see a certificate error\n", browser)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// findDuplicates returns, in first-seen order, one instance of each
+// certificate that appears more than once, along with its copy count.
+func findDuplicates(certs []*x509.Certificate) ([]*x509.Certificate, map[string]int) {
+	counts := map[string]int{}
+	for _, cert := range certs {
+		counts[certFingerprint(cert)]++
+	}
+	var dups []*x509.Certificate
+	reported := map[string]bool{}
+	for _, cert := range certs {
+		fp := certFingerprint(cert)
+		if counts[fp] > 1 && !reported[fp] {
+			dups = append(dups, cert)
+			reported[fp] = true
+		}
+	}
+	return dups, counts
+}
+
+// sourceCount is how many copies of a certificate one labeled source holds.
+type sourceCount struct {
+	label string
+	n     int
+}
+
+// recordSource counts one more copy of the certificate fp in the source
+// label, and reports whether fp had already been seen in any source.
+func recordSource(sources map[string][]sourceCount, fp, label string) bool {
+	counts, seen := sources[fp]
+	for i := range counts {
+		if counts[i].label == label {
+			counts[i].n++
+			return seen
+		}
+	}
+	sources[fp] = append(counts, sourceCount{label, 1})
+	return seen
+}
+
+// reportDuplicates lists every certificate that appears more than once in
+// the bundle, typically after naive concatenation of overlapping bundles.
+func reportDuplicates(certs []*x509.Certificate) {
+	dups, counts := findDuplicates(certs)
+	if len(dups) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Duplicate Certificates ===\n")
+	extra := 0
+	for _, cert := range dups {
+		n := counts[certFingerprint(cert)]
+		extra += n - 1
+		fmt.Fprintf(out, "  🔁 %d copies: %s\n", n, cert.Subject.String())
+	}
+	fmt.Fprintf(out, "  → Run with -dedup to write the bundle without the %d extra copies\n", extra)
+}
+
+// writeDeduplicated writes certs to w as PEM, keeping only the first copy
+// of each certificate.
+func writeDeduplicated(w io.Writer, certs []*x509.Certificate) error {
+	seen := map[string]bool{}
+	for _, cert := range certs {
+		fp := certFingerprint(cert)
+		if seen[fp] {
+			continue
+		}
+		seen[fp] = true
+		if err := pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issuedBy reports whether candidate issued cert, matching AuthorityKeyId
+// to SubjectKeyId and falling back to the issuer DN when either key ID is
+// absent.
+func issuedBy(cert, candidate *x509.Certificate) bool {
+	if len(cert.AuthorityKeyId) > 0 && len(candidate.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, candidate.SubjectKeyId)
+	}
+	return bytes.Equal(cert.RawIssuer, candidate.RawSubject)
+}
+
+// findIssuer returns the index of cert's issuer in certs, or -1 when cert
+// is self-signed or its issuer is not in the bundle.
+func findIssuer(cert *x509.Certificate, certs []*x509.Certificate) int {
+	if bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return -1
+	}
+	for i, candidate := range certs {
+		if candidate != cert && issuedBy(cert, candidate) {
+			return i
+		}
+	}
+	return -1
+}
+
+// chainOrder returns the indexes of certs rearranged so each chain runs
+// leaf first with every certificate followed by its issuer. Chains keep
+// the bundle order of their leaves; an intermediate or root shared with an
+// earlier chain is not repeated.
+func chainOrder(certs []*x509.Certificate) []int {
+	issuer := make([]int, len(certs))
+	issuedOther := make([]bool, len(certs))
+	for i, cert := range certs {
+		issuer[i] = findIssuer(cert, certs)
+		if issuer[i] >= 0 {
+			issuedOther[issuer[i]] = true
+		}
+	}
+
+	placed := make([]bool, len(certs))
+	var order []int
+	walk := func(i int) {
+		for ; i >= 0 && !placed[i]; i = issuer[i] {
+			placed[i] = true
+			order = append(order, i)
+		}
+	}
+	for i := range certs {
+		if !issuedOther[i] {
+			walk(i)
+		}
+	}
+	// Anything left is part of an issuing loop, such as two cross-signs
+	for i := range certs {
+		walk(i)
+	}
+	return order
+}
+
+// reportChainOrder warns about certificates that are not immediately
+// followed by their issuer, which many TLS stacks require of a served
+// chain, and suggests the leaf-first order. Certificates whose issuer is
+// not in the bundle, such as the roots of a trust bundle, start a new chain
+// and are never out of order.
+func reportChainOrder(certs []*x509.Certificate) {
+	var problems []string
+	for i, cert := range certs {
+		j := findIssuer(cert, certs)
+		if j < 0 || (i+1 < len(certs) && issuedBy(cert, certs[i+1])) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("Certificate #%d (%s) is not followed by its issuer, Certificate #%d (%s)",
+			i+1, displayName(cert), j+1, displayName(certs[j])))
+	}
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Chain Order ===\n")
+	for _, p := range problems {
+		fmt.Fprintf(out, "  ⚠️  %s\n", p)
+	}
+	fmt.Fprintf(out, "  → Leaf-first order:\n")
+	for n, i := range chainOrder(certs) {
+		fmt.Fprintf(out, "     %d. Certificate #%d: %s\n", n+1, i+1, certs[i].Subject.String())
+	}
+	fmt.Fprintf(out, "  → Run with -reorder to write the bundle in this order\n")
+}
+
+// checkMaxCerts fails when the bundle holds more than max certificates,
+// pointing at duplicates that inflate the count.
+func checkMaxCerts(certs []*x509.Certificate, max int) bool {
+	fmt.Fprintf(out, "\n=== Bundle Size (max %d) ===\n", max)
+	if len(certs) <= max {
+		fmt.Fprintf(out, "  ✅ %d certificates\n", len(certs))
+		return true
+	}
+
+	fmt.Fprintf(out, "  ❌ %d certificates exceeds the maximum of %d\n", len(certs), max)
+	dups, counts := findDuplicates(certs)
+	if len(dups) == 0 {
+		fmt.Fprintln(out, "  → No duplicates found; prune unused CAs to shrink the bundle")
+		return false
+	}
+	extra := 0
+	for _, cert := range dups {
+		n := counts[certFingerprint(cert)]
+		extra += n - 1
+		fmt.Fprintf(out, "  🔁 %d copies: %s\n", n, cert.Subject.String())
+	}
+	fmt.Fprintf(out, "  → Deduplicating would remove %d certificates (%d remaining)\n", extra, len(certs)-extra)
+	return false
+}
+
+// allowsServerAuth reports whether the certificate's EKUs permit use as a
+// TLS server certificate. An empty EKU list means "any usage".
+func allowsServerAuth(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 {
+		return true
+	}
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageServerAuth || eku == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+var extKeyUsageNameMap = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "ServerAuth",
+	x509.ExtKeyUsageClientAuth:      "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:     "CodeSigning",
+	x509.ExtKeyUsageEmailProtection: "EmailProtection",
+	x509.ExtKeyUsageIPSECEndSystem:  "IPSECEndSystem",
+	x509.ExtKeyUsageIPSECTunnel:     "IPSECTunnel",
+	x509.ExtKeyUsageIPSECUser:       "IPSECUser",
+	x509.ExtKeyUsageTimeStamping:    "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+func extKeyUsageNames(ekus []x509.ExtKeyUsage) []string {
+	var names []string
+	for _, eku := range ekus {
+		if name, ok := extKeyUsageNameMap[eku]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("Unknown(%d)", eku))
+		}
+	}
+	return names
+}
+
+// labeledBundle is a bundle file plus the source label given via -bundle.
+type labeledBundle struct {
+	name string
+	path string
+}
+
+// labeledBundles implements flag.Value for the repeatable -bundle flag.
+type labeledBundles []labeledBundle
+
+func (b *labeledBundles) String() string {
+	var parts []string
+	for _, lb := range *b {
+		parts = append(parts, lb.name+"="+lb.path)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (b *labeledBundles) Set(value string) error {
+	name, path, ok := strings.Cut(value, "=")
+	if !ok || name == "" || path == "" {
+		return fmt.Errorf("expected name=path, got %q", value)
+	}
+	*b = append(*b, labeledBundle{name: name, path: path})
+	return nil
+}
+
+// parseBundle decodes every CERTIFICATE block in PEM data. Certificates
+// that fail to parse are reported and skipped. Data with no PEM block at
+// all is parsed as one or more concatenated DER certificates instead.
+func parseBundle(data []byte) []*x509.Certificate {
+	if block, _ := pem.Decode(data); block == nil && len(bytes.TrimSpace(data)) > 0 {
+		certs, err := x509.ParseCertificates(data)
+		if err != nil {
+			fmt.Fprintf(errOut, "Error parsing certificate: input is not PEM and could not be parsed as DER: %v\n", err)
+			parseErrors++
+			return nil
+		}
+		fmt.Fprintf(out, "ℹ️  Detected encoding: DER (%d certificates)\n\n", len(certs))
+		return certs
+	}
+
+	var certs []*x509.Certificate
+	rest := data
+	
+	// Parse all PEM blocks
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		
+		if block.Type != "CERTIFICATE" && block.Type != "TRUSTED CERTIFICATE" {
+			if verbose {
+				fmt.Fprintf(out, "ℹ️  Skipped %s PEM block (not a certificate)\n", block.Type)
+			}
+			continue
+		}
+		
+		// Parse the certificate
+		cert, err := parseCertificateBlock(block)
+		if err != nil {
+			fmt.Fprintf(errOut, "Error parsing certificate: %v\n", err)
+			parseErrors++
+			continue
+		}
+		
+		certs = append(certs, cert)
+	}
+
+	// pem.Decode returns nil both at a clean end of input and when the
+	// remainder is not valid PEM (e.g. a truncated final cert), so check
+	// what was left over
+	reportTrailingData(data, rest)
+
+	return certs
+}
+
+// parseCertificateBlock parses a CERTIFICATE block, or an OpenSSL TRUSTED
+// CERTIFICATE block as written by trust extract and openssl x509 -trustout,
+// which is the certificate's DER followed by its trust settings.
+func parseCertificateBlock(block *pem.Block) (*x509.Certificate, error) {
+	if block.Type != "TRUSTED CERTIFICATE" {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	var raw asn1.RawValue
+	aux, err := asn1.Unmarshal(block.Bytes, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED CERTIFICATE block: %v", err)
+	}
+	if verbose && len(aux) > 0 {
+		fmt.Fprintln(out, "ℹ️  TRUSTED CERTIFICATE block: using the certificate and ignoring its trust settings")
+	}
+	return x509.ParseCertificate(raw.FullBytes)
+}
+
+// reportTrailingData warns when non-whitespace content remains after the
+// last decodable PEM block.
+func reportTrailingData(data, rest []byte) {
+	trimmed := bytes.TrimLeft(rest, " \t\r\n")
+	if len(bytes.TrimSpace(trimmed)) == 0 {
+		return
+	}
+	offset := len(data) - len(trimmed)
+	parseErrors++
+	fmt.Fprintf(errOut, "⚠️  Trailing %d bytes at offset %d could not be decoded as PEM (truncated or corrupted bundle?)\n\n", len(trimmed), offset)
+}
+
+// certFingerprint returns the normalized (uppercase hex, no separators)
+// SHA-256 fingerprint of the certificate's DER encoding.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// colonHex formats a digest the way openssl prints fingerprints: uppercase
+// hex bytes separated by colons.
+func colonHex(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// normalizeFingerprint strips separators and case so that fingerprints
+// copied from openssl ("AB:CD:...") or spreadsheets ("abcd...") compare equal.
+func normalizeFingerprint(fp string) string {
+	fp = strings.ReplaceAll(fp, ":", "")
+	fp = strings.ReplaceAll(fp, " ", "")
+	return strings.ToUpper(fp)
+}
+
+// loadAnnotations reads a flat YAML map of fingerprint -> note, e.g.
+//
+//	"AB:CD:EF:...": "Platform team - internal ingress CA"
+//	0123abcd...: partner CA, expires with contract
+//
+// Only simple "key: value" lines are supported; comments and blank lines
+// are ignored.
+func loadAnnotations(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	annotations := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		// Fingerprints may themselves contain colons when quoted, so split
+		// on the first ": " rather than the first ":"
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"fingerprint: note\"", path, lineNum)
+		}
+		key = normalizeFingerprint(strings.Trim(strings.TrimSpace(key), "\"'"))
+		value = strings.Trim(strings.TrimSpace(value), "\"'")
+		annotations[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestIsLetsEncrypt(t *testing.T) {
+	tests := []struct {
+		name   string
+		issuer pkix.Name
+		want   bool
+	}{
+		{"empty issuer", pkix.Name{}, false},
+		{"organization", pkix.Name{Organization: []string{"Let's Encrypt"}, CommonName: "X1"}, true},
+		{"ISRG root", pkix.Name{Organization: []string{"Internet Security Research Group"}, CommonName: "ISRG Root X1"}, true},
+		{"R3 exact", pkix.Name{CommonName: "R3"}, true},
+		{"R10 exact", pkix.Name{CommonName: "R10"}, true},
+		{"R11 exact", pkix.Name{CommonName: "R11"}, true},
+		{"E1 exact", pkix.Name{CommonName: "E1"}, true},
+		{"E2 exact", pkix.Name{CommonName: "E2"}, true},
+		{"other CA", pkix.Name{Organization: []string{"DigiCert Inc"}, CommonName: "DigiCert Global Root G2"}, false},
+		{"lowercase name", pkix.Name{CommonName: "r3"}, false},
+		{"internal CA", pkix.Name{CommonName: "Internal Root"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &x509.Certificate{Issuer: tt.issuer}
+			if got := isLetsEncrypt(cert); got != tt.want {
+				t.Errorf("isLetsEncrypt(%q) = %v, want %v", tt.issuer.String(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeakCrypto(t *testing.T) {
+	rsaKey := func(bits int) *rsa.PublicKey {
+		return &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), E: 65537}
+	}
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		problems int
+	}{
+		{"strong RSA", &x509.Certificate{SignatureAlgorithm: x509.SHA256WithRSA, PublicKey: rsaKey(2048)}, 0},
+		{"ECDSA", &x509.Certificate{SignatureAlgorithm: x509.ECDSAWithSHA384, PublicKey: &ecdsa.PublicKey{Curve: elliptic.P384()}}, 0},
+		{"SHA-1 signature", &x509.Certificate{SignatureAlgorithm: x509.SHA1WithRSA, PublicKey: rsaKey(4096)}, 1},
+		{"MD5 signature", &x509.Certificate{SignatureAlgorithm: x509.MD5WithRSA, PublicKey: rsaKey(2048)}, 1},
+		{"small RSA key", &x509.Certificate{SignatureAlgorithm: x509.SHA256WithRSA, PublicKey: rsaKey(1024)}, 1},
+		{"SHA-1 and small key", &x509.Certificate{SignatureAlgorithm: x509.SHA1WithRSA, PublicKey: rsaKey(1024)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weakCrypto(tt.cert); len(got) != tt.problems {
+				t.Errorf("weakCrypto() = %q, want %d problems", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	cert := func(subject, issuer string) *x509.Certificate {
+		return &x509.Certificate{
+			RawSubject:     []byte(subject),
+			RawIssuer:      []byte(issuer),
+			SubjectKeyId:   []byte(subject),
+			AuthorityKeyId: []byte(issuer),
+		}
+	}
+	root, inter, leaf := cert("root", "root"), cert("inter", "root"), cert("leaf", "inter")
+	other := cert("other", "elsewhere")
+	tests := []struct {
+		name  string
+		certs []*x509.Certificate
+		want  []int
+	}{
+		{"already ordered", []*x509.Certificate{leaf, inter, root}, []int{0, 1, 2}},
+		{"root first", []*x509.Certificate{root, leaf, inter}, []int{1, 2, 0}},
+		{"reversed", []*x509.Certificate{root, inter, leaf}, []int{2, 1, 0}},
+		{"unrelated chains keep bundle order", []*x509.Certificate{other, inter, leaf}, []int{0, 2, 1}},
+		{"roots only", []*x509.Certificate{root, cert("root2", "root2")}, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chainOrder(tt.certs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chainOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCertificateBlock(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Test Root"}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An empty X509_CERT_AUX SEQUENCE, as openssl -trustout appends
+	trusted := append(append([]byte{}, der...), 0x30, 0x00)
+
+	tests := []struct {
+		name    string
+		block   *pem.Block
+		wantErr bool
+	}{
+		{"certificate", &pem.Block{Type: "CERTIFICATE", Bytes: der}, false},
+		{"trusted certificate", &pem.Block{Type: "TRUSTED CERTIFICATE", Bytes: trusted}, false},
+		{"trust settings on a plain certificate", &pem.Block{Type: "CERTIFICATE", Bytes: trusted}, true},
+		{"truncated trusted certificate", &pem.Block{Type: "TRUSTED CERTIFICATE", Bytes: der[:len(der)/2]}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := parseCertificateBlock(tt.block)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseCertificateBlock() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCertificateBlock() error = %v", err)
+			}
+			if cert.Subject.CommonName != "Test Root" {
+				t.Errorf("Subject CN = %q, want %q", cert.Subject.CommonName, "Test Root")
+			}
+		})
+	}
+}
+
+func TestParseSerial(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []int64
+	}{
+		{"0A:1B", []int64{0x0a1b}},
+		{"0a:1b", []int64{0x0a1b}},
+		{"0x1F", []int64{0x1f}},
+		{"ff", []int64{0xff}},
+		{"4096", []int64{0x4096, 4096}},
+		{" 10 ", []int64{0x10, 10}},
+		{"", nil},
+		{"0x", nil},
+		{"-5", nil},
+		{"12:zz", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSerial(tt.value)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("parseSerial(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSerial(%q) error = %v", tt.value, err)
+			}
+			var gotInts []int64
+			for _, n := range got {
+				gotInts = append(gotInts, n.Int64())
+			}
+			if !reflect.DeepEqual(gotInts, tt.want) {
+				t.Errorf("parseSerial(%q) = %v, want %v", tt.value, gotInts, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSource(t *testing.T) {
+	sources := map[string][]sourceCount{}
+	adds := []struct {
+		fp, label string
+		seen      bool
+	}{
+		{"aa", "ocp", false},
+		{"aa", "ocp", true},
+		{"bb", "ocp", false},
+		{"aa", "odh", true},
+		{"bb", "odh", true},
+		{"bb", "odh", true},
+	}
+	for _, a := range adds {
+		if got := recordSource(sources, a.fp, a.label); got != a.seen {
+			t.Errorf("recordSource(%s, %s) = %v, want %v", a.fp, a.label, got, a.seen)
+		}
+	}
+	want := map[string][]sourceCount{
+		"aa": {{"ocp", 2}, {"odh", 1}},
+		"bb": {{"ocp", 1}, {"odh", 2}},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+}
@@ -0,0 +1,4392 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/fips140"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/jctanner/odh-security-2.0/test-scripts/tlsprobe"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	defaultServiceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	defaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	kubernetesAPIServer            = "https://kubernetes.default.svc:443"
+	oauthDiscoveryPath             = "/.well-known/oauth-authorization-server"
+	defaultTimeout                 = 10 * time.Second
+
+	// Where RHEL-based images keep the extracted trust bundle. On OpenShift
+	// this is also where a ConfigMap labeled for trusted-CA injection is
+	// typically mounted.
+	defaultInjectedBundlePath = "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem"
+)
+
+// serviceAccountCAPath and serviceAccountTokenPath locate the pod's service
+// account credentials. They can be pointed at saved copies (-ca-path,
+// -token-path, or SA_CA_PATH and SA_TOKEN_PATH) to run outside a cluster.
+var (
+	serviceAccountCAPath    = envOrDefault("SA_CA_PATH", defaultServiceAccountCAPath)
+	serviceAccountTokenPath = envOrDefault("SA_TOKEN_PATH", defaultServiceAccountTokenPath)
+)
+
+func envOrDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// readServiceAccountFile reads the CA or token, naming the resolved path
+// and how to override it when the file does not exist.
+func readServiceAccountFile(path, flagName, envName string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist (not running in a pod? override with -%s or %s)", path, flagName, envName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+func readServiceAccountCA() ([]byte, error) {
+	return readServiceAccountFile(serviceAccountCAPath, "ca-path", "SA_CA_PATH")
+}
+
+func readServiceAccountToken() ([]byte, error) {
+	return readServiceAccountFile(serviceAccountTokenPath, "token-path", "SA_TOKEN_PATH")
+}
+
+// timeout is the global request timeout, set from -timeout. Individual
+// probe scenarios may override it with their own -timeout-* flag.
+var timeout = defaultTimeout
+
+// discoveryRetries is how many times a failed discovery request is retried
+// after network errors and 5xx responses (-discovery-retries).
+var discoveryRetries = 3
+
+// discoveryBackoff is the wait before the first discovery retry; it doubles
+// for each further retry.
+const discoveryBackoff = time.Second
+
+// maxHandshakeLatency, when non-zero, fails the run if any probe's TLS
+// handshake is slower, even when the handshake itself succeeds.
+var maxHandshakeLatency time.Duration
+
+// latencyExceeded records whether any probe breached maxHandshakeLatency.
+var latencyExceeded atomic.Bool
+
+// noKeepAlive disables connection reuse on the probe transports so every
+// request does a fresh handshake (-no-keepalive).
+var noKeepAlive bool
+
+// ja3Profile names the browser whose ClientHello the probes approximate
+// (-ja3-profile); empty means Go's default ClientHello.
+var ja3Profile string
+
+// checkCloseNotify enables the close_notify check after the probes
+// (-check-close-notify).
+var checkCloseNotify bool
+
+// clockOffset shifts the time used to validate certificates, to simulate a
+// client whose clock is skewed (-clock-offset).
+var clockOffset time.Duration
+
+// validationTime is the time certificates are validated at.
+func validationTime() time.Time {
+	return time.Now().Add(clockOffset)
+}
+
+// strictHostname requires DNS-named targets to be matched by a DNS SAN,
+// never by the CN or an IP SAN (-strict-hostname).
+var strictHostname bool
+
+// clientCertificate is offered to servers that request one, for endpoints
+// that require mutual TLS (-client-cert and -client-key).
+var clientCertificate *tls.Certificate
+
+// clientCertRequested records whether the server asked for the client
+// certificate during the current target's probes. It is reset per target
+// rather than per probe because the scenarios run concurrently.
+var clientCertRequested atomic.Bool
+
+// proxyOverride, when set, is used for every request in place of the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment (-proxy).
+var proxyOverride *url.URL
+
+// proxyForRequest picks the proxy for a request: -proxy if given, else the
+// environment, as curl and kube-auth-proxy itself would.
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	if proxyOverride != nil {
+		return proxyOverride, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// describeProxy reports which proxy, if any, requests to target go through.
+func describeProxy(target string) string {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return "unknown (" + err.Error() + ")"
+	}
+	proxy, err := proxyForRequest(req)
+	switch {
+	case err != nil:
+		return "invalid proxy environment: " + err.Error()
+	case proxy == nil:
+		return "none (direct connection)"
+	case proxyOverride != nil:
+		return proxy.Redacted() + " (from -proxy)"
+	default:
+		return proxy.Redacted() + " (from environment)"
+	}
+}
+
+// dumpPeerChain prints the chain a server presented when its certificate
+// fails verification (-dump-peer-chain).
+var dumpPeerChain bool
+
+// showTokenClaims prints the unverified claims of the bearer token used for
+// discovery, never the token itself (-show-token-claims).
+var showTokenClaims bool
+
+// dumpedChains records the targets whose chain has already been dumped, so
+// a target failing several scenarios is captured once.
+var dumpedChains = map[string]bool{}
+
+// dumpedChainsMu guards dumpedChains against the concurrent scenarios.
+var dumpedChainsMu sync.Mutex
+
+// results collects the outcome of each probe scenario for -output-configmap.
+var results []probeResult
+
+// resultsMu guards results against the concurrent scenarios.
+var resultsMu sync.Mutex
+
+// systemCertPoolLoader loads the platform trust store. It is a variable so
+// tests can simulate platforms where the store is unavailable.
+var systemCertPoolLoader = x509.SystemCertPool
+
+// systemCAFallback is an optional PEM bundle used in place of the platform
+// trust store when systemCertPoolLoader fails (-system-ca-fallback).
+var systemCAFallback string
+
+// errSystemTrustUnavailable means neither the platform trust store nor a
+// fallback bundle could be loaded, so system-trust scenarios are skipped
+// rather than run against an empty pool.
+var errSystemTrustUnavailable = errors.New("system trust store unavailable on this platform")
+
+// report receives the human-readable report. It goes to stderr with
+// -log-format json or text so stdout only carries records.
+var report io.Writer = os.Stdout
+
+// out, warnOut and errOut are report for its informational, warning and
+// error lines, or io.Discard when -log-level or -quiet leaves that level
+// out. Output buffered before it reaches report picks its level with at.
+var (
+	out     io.Writer = os.Stdout
+	warnOut io.Writer = os.Stdout
+	errOut  io.Writer = os.Stdout
+)
+
+// resultOut receives the final per-target summary, which -quiet and
+// -log-level never suppress. Structured formats log the summary as records
+// instead, so it is discarded there.
+var resultOut io.Writer = os.Stdout
+
+// logger emits structured records with -log-format=json or text; it is nil
+// in the default human format.
+var logger *slog.Logger
+
+// logLevel is the least severe level reported (-log-level, or error with
+// -quiet).
+var logLevel = slog.LevelInfo
+
+type OAuthDiscovery struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+func main() {
+	mode := flag.String("mode", "probe", "what to run (see modes below)")
+	injectedBundle := flag.String("injected-bundle", defaultInjectedBundlePath, "proxy-ca mode: path to the injected trusted-CA bundle")
+	injectedConfigMap := flag.String("injected-configmap", "", "proxy-ca mode: read the injected bundle from this `namespace/name` ConfigMap instead of -injected-bundle")
+	systemBundle := flag.String("system-bundle", "", "proxy-ca mode: system roots bundle to expect in the injection (default: first of the well-known distro paths)")
+	flag.StringVar(&serviceAccountCAPath, "ca-path", serviceAccountCAPath, "service account CA `file` (env SA_CA_PATH)")
+	flag.StringVar(&serviceAccountTokenPath, "token-path", serviceAccountTokenPath, "service account token `file` (env SA_TOKEN_PATH)")
+	flag.StringVar(&bearerToken, "token", "", "bearer `token` for the Kubernetes API, used verbatim instead of the service account or kubeconfig credentials")
+	flag.StringVar(&bearerTokenFile, "token-file", "", "read the bearer token for the Kubernetes API from this `file` instead of using the service account or kubeconfig credentials")
+	flag.StringVar(&kubeconfigPath, "kubeconfig", "", "reach the Kubernetes API with the current context of this kubeconfig `file` instead of the pod's service account (default: in-cluster when the token file exists, else $KUBECONFIG or ~/.kube/config)")
+	flag.DurationVar(&timeout, "timeout", defaultTimeout, "timeout for discovery and each probe")
+	flag.IntVar(&discoveryRetries, "discovery-retries", discoveryRetries, "retry OAuth discovery this many times on network errors and 5xx responses, with exponential backoff")
+	timeoutServiceCA := flag.Duration("timeout-service-ca", 0, "override -timeout for the service account CA only probe")
+	timeoutUnion := flag.Duration("timeout-union", 0, "override -timeout for the system trust store + service account CA probe")
+	timeoutSystem := flag.Duration("timeout-system", 0, "override -timeout for the system trust store only probe")
+	flag.DurationVar(&maxHandshakeLatency, "max-handshake-latency", 0, "fail if any probe's TLS handshake takes longer than this, even when it succeeds")
+	webhookConfig := flag.String("webhook", "", "webhook mode: `kind/name` of the webhook configuration, kind is validating or mutating")
+	jwtFile := flag.String("jwt-file", "-", "verify-x5c mode: `file` holding the JWT or its header segment (- for stdin)")
+	trustBundle := flag.String("trust-bundle", "", "verify-x5c mode: PEM `bundle` of roots to verify the x5c chain against")
+	var reconcileSources sourceList
+	flag.Var(&reconcileSources, "source", "reconcile mode: CA `source` (repeatable): file:PATH, configmap:NS/NAME[#KEY], system, or system-root:COMMON-NAME")
+	reconcileTarget := flag.String("reconcile-target", "", "reconcile mode: `namespace/name` of the ConfigMap to keep up to date (key ca-bundle.crt)")
+	reconcileInterval := flag.Duration("reconcile-interval", 0, "reconcile mode: repeat every `interval` (default: reconcile once)")
+	chainFile := flag.String("chain-file", "", "decode-chain mode: `file` of concatenated DER certificates captured from a Certificate handshake message")
+	serverName := flag.String("server-name", "", "decode-chain mode: hostname to verify the captured leaf against (optional)")
+	flag.StringVar(&systemCAFallback, "system-ca-fallback", "", "PEM `bundle` to use as the system trust store when the platform store cannot be loaded")
+	component := flag.String("component", "", "component mode: component whose CA bundle to validate, or \"list\" to show known components")
+	flag.BoolVar(&noKeepAlive, "no-keepalive", false, "use a fresh connection for every probe request (disables keep-alives and idle connection reuse)")
+	flag.StringVar(&ja3Profile, "ja3-profile", "", "shape the probe ClientHello like a browser: chrome, firefox or safari (default: Go's own)")
+	proxyFlag := flag.String("proxy", "", "send all HTTP requests through this proxy `URL` (http, https, socks5 or socks5h), overriding HTTP_PROXY/HTTPS_PROXY/NO_PROXY")
+	logFormat := flag.String("log-format", "human", "output `format`: human (the decorated report), json or text (one structured record per line on stdout, with the report on stderr)")
+	logLevelFlag := flag.String("log-level", "info", "least severe `level` reported: debug, info, warn or error")
+	quiet := flag.Bool("quiet", false, "print only errors and the final summary (same as -log-level=error)")
+	jsonOutput := flag.Bool("json", false, "probe mode: print only a single JSON document summarizing discovery and every scenario, instead of the report")
+	flag.BoolVar(&showTokenClaims, "show-token-claims", false, "print the exp, iss and service account of the discovery bearer token, decoded without verification (the token itself is redacted)")
+	flag.BoolVar(&dumpPeerChain, "dump-peer-chain", false, "when a probe fails certificate verification, re-dial without verification and print the served chain as PEM for verify-root-ca")
+	flag.BoolVar(&checkCloseNotify, "check-close-notify", false, "after the probes, report whether the server ends the connection with a TLS close_notify alert")
+	checkChainOrder := flag.Bool("check-chain-order", false, "probe mode: check the served chain is leaf-first, correctly ordered and does not include the root")
+	clientCert := flag.String("client-cert", "", "PEM client certificate `file` to present for mutual TLS (requires -client-key)")
+	clientKey := flag.String("client-key", "", "PEM private key `file` for -client-cert")
+	flag.BoolVar(&strictHostname, "strict-hostname", false, "require DNS-named targets to be matched by a DNS SAN only, never the CN or an IP SAN")
+	flag.DurationVar(&clockOffset, "clock-offset", 0, "shift the time used for certificate validation by this `duration` (may be negative) to simulate client clock skew")
+	remediate := flag.Bool("remediate", false, "probe mode: print a patch that fixes the diagnosed trust problem, for review before applying")
+	proxyDeployment := flag.String("proxy-deployment", "openshift-ingress/kube-auth-proxy", "-remediate: `namespace/name` of the kube-auth-proxy Deployment")
+	proxyContainer := flag.String("proxy-container", "kube-auth-proxy", "-remediate: container in -proxy-deployment that runs kube-auth-proxy")
+	trustConfigMap := flag.String("trust-configmap", "", "-remediate: `namespace/name` of the CA bundle ConfigMap a missing root should be appended to")
+	fleetResource := flag.String("fleet-resource", "routes", "fleet mode: resource to discover endpoints from: routes or services")
+	fleetSelector := flag.String("fleet-selector", "", "fleet mode: label `selector` for the resources to probe")
+	fleetNamespaces := flag.String("fleet-namespaces", "", "fleet mode: comma-separated `namespaces` to search (default: all)")
+	fleetConcurrency := flag.Int("fleet-concurrency", 4, "fleet mode: number of endpoints probed in parallel")
+	fleetRate := flag.Float64("fleet-rate", 10, "fleet mode: maximum probes started per second")
+	insecure := flag.Bool("insecure", false, "probe mode: add a fourth scenario with certificate verification DISABLED, to tell trust failures from network failures")
+	metricsFile := flag.String("metrics-file", "", "probe mode: write the results as Prometheus metrics to this `file`, for the node_exporter textfile collector")
+	outputConfigMap := flag.String("output-configmap", "", "probe mode: create or update this `namespace/name` ConfigMap with the structured results (for running as a Job)")
+	expectDiscovery := flag.String("expect-discovery", "", "fail if the live OAuth discovery document differs from this golden `file.json`")
+	discoveryAllow := flag.String("discovery-allow", "", "comma-separated discovery `fields` allowed to differ from -expect-discovery")
+	expectCIDR := flag.String("expect-cidr", "", "fail unless the token endpoint host resolves to an address in this `CIDR`")
+	var targetURLs urlList
+	flag.Var(&targetURLs, "url", "probe this HTTPS `URL` instead of the discovered token endpoint (repeatable; also accepted as positional arguments)")
+	tokenURL := flag.String("token-url", "", "the OAuth token endpoint `URL`, when already known: skips discovery and the service account token check")
+	flag.Usage = func() {
+		fmt.Println("Usage: go run ./cmd/test-tls-connect [flags] [url ...]")
+		fmt.Println()
+		fmt.Println("Modes:")
+		fmt.Println("  probe     discover the OAuth token endpoint and test it against each trust store (default)")
+		fmt.Println("  sigalgs   report which handshake signature algorithms the OAuth endpoint accepts")
+		fmt.Println("  proxy-ca  validate the cluster proxy's trusted-CA injection")
+		fmt.Println("  preflight check the service account CA and token files are present, readable and valid")
+		fmt.Println("  webhook   verify admission webhook serving certs against the configuration's caBundle")
+		fmt.Println("  decode-chain  analyze a captured DER chain offline against each trust store")
+		fmt.Println("  component     validate a known component's CA bundle against the cluster default")
+		fmt.Println("  auth-config   validate OIDC/OpenID issuers against the CA referenced by the cluster auth config")
+		fmt.Println("  token-exchange  validate the chain served on a real (dummy) token request and compare it to a plain handshake")
+		fmt.Println("  fleet         probe every Route or Service matching a label selector across namespaces")
+		fmt.Println("  verify-x5c    verify the x5c certificate chain in a JWT header against a trust bundle")
+		fmt.Println("  reconcile     assemble a deduplicated bundle from -source entries and keep a ConfigMap up to date")
+		fmt.Println()
+		fmt.Println("In probe, sigalgs and token-exchange modes the token endpoint is discovered")
+		fmt.Println("from the Kubernetes API only when no -token-url, -url or positional URL is")
+		fmt.Println("given; otherwise kubernetes.default.svc is not contacted.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := configureLogging(*logFormat, *logLevelFlag, *quiet); err != nil {
+		fmt.Printf("❌ Invalid logging flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *tokenURL != "" {
+		if err := targetURLs.Set(*tokenURL); err != nil {
+			fmt.Printf("❌ Invalid -token-url: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, arg := range flag.Args() {
+		if err := targetURLs.Set(arg); err != nil {
+			fmt.Fprintf(errOut, "❌ Invalid target: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if _, ok := clientHelloProfiles[ja3Profile]; ja3Profile != "" && !ok {
+		fmt.Fprintf(errOut, "❌ Unknown -ja3-profile %q (expected chrome, firefox or safari)\n", ja3Profile)
+		os.Exit(1)
+	}
+
+	// The flag package already rejects unparseable durations; a zero
+	// http.Client timeout would mean "wait forever", so reject it too
+	if timeout <= 0 {
+		fmt.Fprintf(errOut, "❌ Invalid -timeout %s: must be positive\n", timeout)
+		os.Exit(1)
+	}
+	if discoveryRetries < 0 {
+		fmt.Fprintf(errOut, "❌ Invalid -discovery-retries %d: must not be negative\n", discoveryRetries)
+		os.Exit(1)
+	}
+	for name, override := range map[string]time.Duration{
+		"timeout-service-ca": *timeoutServiceCA,
+		"timeout-union":      *timeoutUnion,
+		"timeout-system":     *timeoutSystem,
+	} {
+		if override < 0 {
+			fmt.Fprintf(errOut, "❌ Invalid -%s %s: must not be negative\n", name, override)
+			os.Exit(1)
+		}
+	}
+
+	if *proxyFlag != "" {
+		parsed, err := url.Parse(*proxyFlag)
+		if err != nil || parsed.Host == "" {
+			fmt.Fprintf(errOut, "❌ Invalid -proxy %q: expected a URL such as http://proxy.example:3128\n", *proxyFlag)
+			os.Exit(1)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			fmt.Fprintf(errOut, "❌ Invalid -proxy scheme %q (expected http, https, socks5 or socks5h)\n", parsed.Scheme)
+			os.Exit(1)
+		}
+		proxyOverride = parsed
+	}
+
+	if bearerToken != "" && bearerTokenFile != "" {
+		fmt.Fprintln(errOut, "❌ -token and -token-file are mutually exclusive")
+		os.Exit(1)
+	}
+
+	if (*clientCert == "") != (*clientKey == "") {
+		fmt.Fprintln(errOut, "❌ -client-cert and -client-key must be given together")
+		os.Exit(1)
+	}
+	if *clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(*clientCert, *clientKey)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ Cannot load client certificate: %v\n", err)
+			os.Exit(1)
+		}
+		clientCertificate = &cert
+	}
+
+	var expectNet *net.IPNet
+	if *expectCIDR != "" {
+		var err error
+		if _, expectNet, err = net.ParseCIDR(*expectCIDR); err != nil {
+			fmt.Fprintf(errOut, "❌ Invalid -expect-cidr: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOutput {
+		if *mode != "probe" {
+			fmt.Fprintf(errOut, "❌ -json is only supported in probe mode, not %q\n", *mode)
+			os.Exit(1)
+		}
+		if *logFormat != "human" {
+			fmt.Fprintln(errOut, "❌ -json and -log-format json/text both write to stdout; use one")
+			os.Exit(1)
+		}
+		report, out, warnOut, errOut = io.Discard, io.Discard, io.Discard, io.Discard
+		resultOut = io.Discard
+	}
+
+	// Chains have been seen to validate under one crypto backend and not
+	// the other, so always record which one produced these results
+	fmt.Fprintf(out, "Crypto backend: %s\n", cryptoBackend())
+	if clockOffset != 0 {
+		fmt.Fprintf(out, "Validation time: %s (clock offset %s)\n", validationTime().UTC().Format(time.RFC3339), clockOffset)
+	}
+	fmt.Fprintln(out)
+
+	// Ctrl-C cancels discovery, Kubernetes API calls and in-flight probes
+	// rather than killing the process mid-write; a second Ctrl-C exits
+	// immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	switch *mode {
+	case "probe", "sigalgs", "token-exchange":
+	case "preflight":
+		if !checkServiceAccountFiles() {
+			os.Exit(1)
+		}
+		return
+	case "proxy-ca":
+		ok := checkProxyCAInjection(ctx, *injectedBundle, *injectedConfigMap, *systemBundle)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "webhook":
+		ok := checkWebhookServingCerts(ctx, *webhookConfig)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "decode-chain":
+		if !decodeCapturedChain(*chainFile, *serverName) {
+			os.Exit(1)
+		}
+		return
+	case "reconcile":
+		ok := reconcileTrustBundle(ctx, reconcileSources, *reconcileTarget, *reconcileInterval)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "verify-x5c":
+		if !verifyX5C(*jwtFile, *trustBundle) {
+			os.Exit(1)
+		}
+		return
+	case "component":
+		ok := checkComponentTrust(ctx, *component)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "auth-config":
+		ok := checkAuthConfigTrust(ctx)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "fleet":
+		ok := probeFleet(ctx, *fleetResource, *fleetSelector, *fleetNamespaces, *fleetConcurrency, *fleetRate)
+		exitIfInterrupted(ctx)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	default:
+		fmt.Fprintf(errOut, "❌ Unknown mode %q (expected probe, sigalgs, preflight, proxy-ca, webhook, decode-chain, component, auth-config, token-exchange, fleet, verify-x5c or reconcile)\n", *mode)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(out, "=== TLS Connection Test (Simulating kube-auth-proxy behavior) ===")
+	fmt.Fprintln(out)
+
+	// Explicit targets (-token-url, -url or arguments) skip discovery,
+	// which is only needed to find the token endpoint, and with it the
+	// service account token, so nothing here talks to the API server
+	targets := []string(targetURLs)
+	var discovery *discoveryReport
+	if len(targets) == 0 {
+		api, err := loadKubeAPI()
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot configure Kubernetes API access: %v\n", err)
+			logRecord(slog.LevelError, "kubernetes api access", "status", "fail", "error", err.Error())
+			if *jsonOutput {
+				if err := writeJSONReport(os.Stdout, nil, &discoveryReport{Error: "cannot configure Kubernetes API access: " + err.Error()}, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing JSON report: %v\n", err)
+				}
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "Kubernetes API: %s (%s)\n", api.server, api.source)
+		fmt.Fprintf(out, "Proxy for the Kubernetes API: %s\n\n", describeProxy(api.server))
+
+		// Separate token and RBAC problems from TLS and discovery failures
+		if !checkServiceAccountToken(ctx) {
+			logRecord(slog.LevelError, "service account token preflight failed", "api_server", api.server)
+			if *jsonOutput {
+				if err := writeJSONReport(os.Stdout, nil, &discoveryReport{Error: "service account token preflight failed"}, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing JSON report: %v\n", err)
+				}
+			}
+			os.Exit(1)
+		}
+
+		// Auto-discover OAuth URL from Kubernetes API (just like kube-auth-proxy does)
+		oauthURL, discoveryDoc, err := discoverOAuthURL(ctx)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: OAuth discovery failed: %v\n", err)
+			logRecord(slog.LevelError, "oauth discovery", "status", "fail", "error", err.Error())
+			if *jsonOutput {
+				if err := writeJSONReport(os.Stdout, nil, &discoveryReport{Error: "OAuth discovery failed: " + err.Error()}, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing JSON report: %v\n", err)
+				}
+			}
+			os.Exit(1)
+		}
+		discovery = &discoveryReport{TokenEndpoint: oauthURL}
+		logRecord(slog.LevelInfo, "oauth discovery", "status", "success", "token_endpoint", oauthURL)
+		var doc OAuthDiscovery
+		if json.Unmarshal(discoveryDoc, &doc) == nil {
+			discovery.Issuer = doc.Issuer
+		}
+
+		fmt.Fprintf(out, "✅ Auto-discovered OAuth Token URL: %s\n\n", oauthURL)
+
+		if *expectDiscovery != "" && !compareDiscovery(discoveryDoc, *expectDiscovery, *discoveryAllow) {
+			os.Exit(1)
+		}
+		targets = []string{oauthURL}
+	} else if *expectDiscovery != "" {
+		fmt.Fprintln(warnOut, "⚠️  -expect-discovery is ignored when targets are given with -token-url or -url")
+		fmt.Fprintln(out)
+	}
+
+	if expectNet != nil {
+		for _, target := range targets {
+			if !checkResolvesWithin(ctx, target, expectNet) {
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *mode == "sigalgs" {
+		for _, target := range targets {
+			probeSignatureSchemes(ctx, target)
+			exitIfInterrupted(ctx)
+		}
+		return
+	}
+
+	if *mode == "token-exchange" {
+		ok := true
+		for _, target := range targets {
+			ok = checkTokenExchangeChain(ctx, target) && ok
+			exitIfInterrupted(ctx)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if strictHostname {
+		fmt.Fprintln(out, "Hostname matching: strict (DNS SANs only)")
+	}
+	if noKeepAlive {
+		fmt.Fprintln(out, "Keep-alives: disabled (fresh connection per request)")
+	} else {
+		fmt.Fprintln(out, "Keep-alives: enabled")
+	}
+	if clientCertificate != nil {
+		fmt.Fprintf(out, "Client certificate: %s\n", clientCertificate.Leaf.Subject.String())
+	}
+	fmt.Fprintln(out)
+
+	chainOrderOK := true
+	for _, target := range targets {
+		if len(targets) > 1 {
+			fmt.Fprintf(out, "=== Target: %s ===\n\n", target)
+		}
+
+		fmt.Fprintf(out, "Proxy: %s\n\n", describeProxy(target))
+
+		if ja3Profile != "" {
+			reportJA3(ctx, target)
+		}
+
+		// The scenarios are independent, so run them concurrently: an
+		// endpoint that times out in all three then costs one timeout
+		// rather than three. Each writes to its own buffer, and the
+		// buffers are printed in scenario order once all have finished.
+		type scenario struct {
+			header, description string
+			run                 func(ctx context.Context, w io.Writer, url string)
+			timeout             time.Duration
+			timeoutFlag         string
+		}
+		scenarios := []scenario{
+			// Test 1: Service Account CA only (default kube-auth-proxy behavior)
+			{"--- Test 1: Service Account CA Only ---", "(This simulates default kube-auth-proxy OpenShift provider behavior)",
+				testWithServiceAccountCA, *timeoutServiceCA, "timeout-service-ca"},
+			// Test 2: System Trust Store + Service Account CA (--use-system-trust-store=true)
+			{"--- Test 2: System Trust Store + Service Account CA ---", "(This simulates kube-auth-proxy with --use-system-trust-store=true)",
+				testWithSystemTrustStore, *timeoutUnion, "timeout-union"},
+			// Test 3: System Trust Store Only (for comparison)
+			{"--- Test 3: System Trust Store Only ---", "(This simulates curl without --cacert flag)",
+				testWithSystemOnly, *timeoutSystem, "timeout-system"},
+		}
+		if *insecure {
+			// Test 4: No verification at all (triage only, like curl -k)
+			scenarios = append(scenarios, scenario{"--- Test 4: INSECURE, Certificate Verification Disabled ---", "(This simulates curl -k; it proves connectivity only, never trust)",
+				testInsecure, 0, "timeout"})
+		}
+		clientCertRequested.Store(false)
+		recorded := len(results)
+		outputs := make([]bytes.Buffer, len(scenarios))
+		var wg sync.WaitGroup
+		for i, sc := range scenarios {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := &outputs[i]
+				fmt.Fprintln(at(w, slog.LevelInfo), sc.header)
+				fmt.Fprintln(at(w, slog.LevelInfo), sc.description)
+				probeCtx, cancel := context.WithTimeout(ctx, probeTimeout(w, sc.timeout, sc.timeoutFlag))
+				defer cancel()
+				sc.run(probeCtx, w, target)
+			}()
+		}
+		wg.Wait()
+		for i := range outputs {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			report.Write(outputs[i].Bytes())
+		}
+		sortResults(results[recorded:])
+		exitIfInterrupted(ctx)
+
+		if checkCloseNotify {
+			fmt.Fprintln(out)
+			probeCloseNotify(ctx, target)
+		}
+
+		if *checkChainOrder {
+			fmt.Fprintln(out)
+			chainOrderOK = checkServedChainOrder(ctx, target) && chainOrderOK
+		}
+
+		if *remediate {
+			fmt.Fprintln(out)
+			printRemediation(ctx, target, *proxyDeployment, *proxyContainer, *trustConfigMap)
+		}
+		fmt.Fprintln(out)
+	}
+
+	exitIfInterrupted(ctx)
+	unreachable := printTargetSummary(targets)
+	if *jsonOutput {
+		if err := writeJSONReport(os.Stdout, targets, discovery, unreachable == 0 && chainOrderOK && !latencyExceeded.Load()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *metricsFile != "" {
+		fmt.Fprintln(out)
+		if err := writeMetricsFile(*metricsFile); err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot write metrics: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "✅ Metrics written to %s\n", *metricsFile)
+	}
+
+	if *outputConfigMap != "" {
+		fmt.Fprintln(out)
+		if err := writeResultsConfigMap(ctx, *outputConfigMap, strings.Join(targets, ",")); err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot write results: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "✅ Results written to ConfigMap %s\n", *outputConfigMap)
+	}
+
+	if latencyExceeded.Load() {
+		fmt.Fprintln(out)
+		fmt.Fprintf(errOut, "❌ FAIL: TLS handshake exceeded -max-handshake-latency %s\n", maxHandshakeLatency)
+		os.Exit(1)
+	}
+	if !chainOrderOK || unreachable > 0 {
+		os.Exit(1)
+	}
+}
+
+// exitIfInterrupted exits with the conventional status for SIGINT once ctx
+// has been cancelled by a signal, skipping the remaining checks.
+func exitIfInterrupted(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(errOut, "❌ Interrupted; remaining checks skipped")
+	os.Exit(130)
+}
+
+// urlList implements flag.Value for the repeatable -url flag.
+type urlList []string
+
+func (u *urlList) String() string { return strings.Join(*u, ",") }
+
+func (u *urlList) Set(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("expected an https:// URL, got %q", value)
+	}
+	*u = append(*u, value)
+	return nil
+}
+
+// configureLogging routes the report according to -log-format, -log-level
+// and -quiet.
+func configureLogging(format, level string, quiet bool) error {
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown -log-level %q (expected debug, info, warn or error)", level)
+	}
+	if quiet {
+		logLevel = slog.LevelError
+	}
+
+	// The handlers accept every level; logRecord applies logLevel so the
+	// final summary, logged directly, always gets through
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	switch format {
+	case "human":
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, options))
+	case "text":
+		logger = slog.New(slog.NewTextHandler(os.Stdout, options))
+	default:
+		return fmt.Errorf("unknown -log-format %q (expected human, json or text)", format)
+	}
+	if logger != nil {
+		report = os.Stderr
+		resultOut = io.Discard
+	}
+	out, warnOut, errOut = at(report, slog.LevelInfo), at(report, slog.LevelWarn), at(report, slog.LevelError)
+	return nil
+}
+
+// at returns w for report lines at level, or io.Discard when level is
+// below -log-level.
+func at(w io.Writer, level slog.Level) io.Writer {
+	if level < logLevel {
+		return io.Discard
+	}
+	return w
+}
+
+// logRecord emits a structured record with -log-format json or text when
+// level passes -log-level.
+func logRecord(level slog.Level, msg string, attrs ...any) {
+	if logger != nil && level >= logLevel {
+		logger.Log(context.Background(), level, msg, attrs...)
+	}
+}
+
+// printTargetSummary prints each target's outcome in the three trust-store
+// scenarios and returns how many targets no scenario could reach.
+func printTargetSummary(targets []string) int {
+	scenarios := []string{"service-account-ca", "system-and-service-account-ca", "system-only"}
+	fmt.Fprintln(resultOut, "=== Summary ===")
+	unreachable := 0
+	for _, target := range targets {
+		var outcomes []string
+		attrs := []any{"target", target}
+		ok := false
+		for _, scenario := range scenarios {
+			result := resultFor(scenario, target)
+			if result == "success" {
+				ok = true
+			}
+			outcomes = append(outcomes, scenario+"="+result)
+			attrs = append(attrs, scenario, result)
+		}
+		if ok {
+			fmt.Fprintf(resultOut, "✅ %s\n", target)
+		} else {
+			fmt.Fprintf(resultOut, "❌ %s\n", target)
+			unreachable++
+		}
+		fmt.Fprintf(resultOut, "   %s\n", strings.Join(outcomes, " "))
+		verdict := trustVerdict(resultFor(scenarios[0], target), resultFor(scenarios[1], target), resultFor(scenarios[2], target))
+		fmt.Fprintf(resultOut, "   → %s\n", verdict)
+		attrs = append(attrs, "verdict", verdict)
+		if insecure := resultFor("insecure", target); insecure != "" {
+			// Never counted as reachable: it says nothing about trust
+			fmt.Fprintf(resultOut, "   insecure=%s (verification disabled, not counted)\n", insecure)
+			switch {
+			case !ok && insecure == "success":
+				fmt.Fprintln(resultOut, "   → Reachable without verification: this is a certificate trust problem")
+			case !ok && insecure == "fail":
+				fmt.Fprintln(resultOut, "   → Fails even without verification: look at the network, proxy or TLS protocol, not the CA bundle")
+			}
+			attrs = append(attrs, "insecure", insecure)
+		}
+		if logger != nil {
+			level := slog.LevelInfo
+			if !ok {
+				level = slog.LevelError
+			}
+			logger.Log(context.Background(), level, "target summary", append(attrs, "reachable", ok)...)
+		}
+	}
+	if unreachable > 0 {
+		fmt.Fprintf(resultOut, "\n❌ FAIL: %d of %d targets failed every trust-store scenario\n", unreachable, len(targets))
+		if logger != nil {
+			logger.Error("targets failed every trust-store scenario", "failed", unreachable, "targets", len(targets))
+		}
+	}
+	return unreachable
+}
+
+// jsonSchemaVersion is the schemaVersion of the -json document. Adding
+// fields is compatible; renaming, removing or changing the meaning of one
+// is not, and needs a new version.
+const jsonSchemaVersion = 1
+
+// runReport is the -json document: the whole run in one object, for
+// dashboards that store and trend results.
+type runReport struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Timestamp     time.Time        `json:"timestamp"`
+	CryptoBackend string           `json:"cryptoBackend"`
+	Success       bool             `json:"success"`
+	Discovery     *discoveryReport `json:"discovery,omitempty"` // absent when targets were given explicitly
+	Targets       []targetReport   `json:"targets"`
+}
+
+type discoveryReport struct {
+	Issuer        string `json:"issuer,omitempty"`
+	TokenEndpoint string `json:"tokenEndpoint,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type targetReport struct {
+	URL       string           `json:"url"`
+	Verdict   string           `json:"verdict"`
+	Scenarios []scenarioReport `json:"scenarios"`
+}
+
+type scenarioReport struct {
+	Scenario         string     `json:"scenario"`
+	Result           string     `json:"result"` // success, fail or skipped
+	Success          bool       `json:"success"`
+	Error            string     `json:"error,omitempty"`
+	HTTPStatus       int        `json:"httpStatus,omitempty"`
+	TLSVersion       string     `json:"tlsVersion,omitempty"`
+	HandshakeMS      int64      `json:"handshakeMs,omitempty"`
+	PeerCertNotAfter *time.Time `json:"peerCertNotAfter,omitempty"`
+}
+
+// writeJSONReport writes the -json document to w from the recorded results.
+func writeJSONReport(w io.Writer, targets []string, discovery *discoveryReport, success bool) error {
+	report := runReport{
+		SchemaVersion: jsonSchemaVersion,
+		Timestamp:     time.Now().UTC(),
+		CryptoBackend: cryptoBackend(),
+		Success:       success,
+		Discovery:     discovery,
+		Targets:       []targetReport{},
+	}
+	for _, target := range targets {
+		t := targetReport{
+			URL: target,
+			Verdict: trustVerdict(resultFor("service-account-ca", target),
+				resultFor("system-and-service-account-ca", target), resultFor("system-only", target)),
+			Scenarios: []scenarioReport{},
+		}
+		for _, r := range results {
+			if r.Target != target {
+				continue
+			}
+			sr := scenarioReport{
+				Scenario:    r.Scenario,
+				Result:      r.Result,
+				Success:     r.Result == "success",
+				HTTPStatus:  r.statusCode,
+				TLSVersion:  r.tlsVersion,
+				HandshakeMS: r.HandshakeMS,
+			}
+			if r.Result != "success" {
+				sr.Error = r.Detail
+			}
+			if !r.leafNotAfter.IsZero() {
+				notAfter := r.leafNotAfter.UTC()
+				sr.PeerCertNotAfter = &notAfter
+			}
+			t.Scenarios = append(t.Scenarios, sr)
+		}
+		report.Targets = append(report.Targets, t)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(report)
+}
+
+// trustVerdict draws the conclusion from the three trust-store scenarios
+// (service account CA only, system store plus service account CA, system
+// store only) and names the kube-auth-proxy configuration it calls for.
+func trustVerdict(serviceAccountCA, union, systemOnly string) string {
+	switch {
+	case serviceAccountCA == "success" && systemOnly == "success":
+		return "Trusted by both the service account CA and the system trust store: no change needed"
+	case serviceAccountCA == "success":
+		return "Trusted via the service account CA: the default configuration works, no change needed"
+	case union == "success":
+		return "Service account CA only failed but the system trust store succeeded: set --use-system-trust-store=true on kube-auth-proxy"
+	case union == "skipped":
+		return "Service account CA only failed and the system trust store is unavailable here, so whether --use-system-trust-store=true would help is unknown"
+	case systemOnly == "success":
+		// The union is a superset of the system store, so this is not
+		// a trust problem
+		return "System trust store only succeeded but the combined store failed: the service account CA could not be read or the failure is intermittent; rerun before changing anything"
+	case union == "fail":
+		return "No trust store trusts the endpoint: add its root CA to the CA bundle kube-auth-proxy mounts (see -remediate)"
+	default:
+		return "Not enough scenario results to draw a conclusion"
+	}
+}
+
+// probeResult is the outcome of one probe scenario, as written to the
+// -output-configmap.
+type probeResult struct {
+	Scenario    string `json:"scenario"`
+	Target      string `json:"target"`
+	Result      string `json:"result"` // success, fail or skipped
+	Detail      string `json:"detail,omitempty"`
+	HandshakeMS int64  `json:"handshakeMs,omitempty"`
+
+	// Set for successful probes and only used by -metrics-file and -json
+	statusCode   int
+	tlsVersion   string
+	leafNotAfter time.Time
+}
+
+// recordResult records a probe that failed or was skipped, with the error
+// as its detail.
+func recordResult(scenario, target, result, detail string, handshake time.Duration) {
+	addResult(probeResult{
+		Scenario:    scenario,
+		Target:      target,
+		Result:      result,
+		Detail:      detail,
+		HandshakeMS: handshake.Milliseconds(),
+	})
+	level := slog.LevelError
+	if result == "skipped" {
+		level = slog.LevelWarn
+	}
+	logRecord(level, "probe result", "scenario", scenario, "target", target, "status", result,
+		"error", detail, "handshake_ms", handshake.Milliseconds())
+}
+
+// recordSuccess records a successful probe along with the negotiated TLS
+// version and the leaf certificate's expiry.
+func recordSuccess(scenario, target string, result *tlsprobe.ProbeResult) {
+	r := probeResult{
+		Scenario:    scenario,
+		Target:      target,
+		Result:      "success",
+		Detail:      fmt.Sprintf("HTTP %d", result.StatusCode),
+		HandshakeMS: result.Handshake.Milliseconds(),
+		statusCode:  result.StatusCode,
+		tlsVersion:  result.TLSVersionName(),
+	}
+	if len(result.PeerCertificates) > 0 {
+		r.leafNotAfter = result.PeerCertificates[0].NotAfter
+	}
+	addResult(r)
+	logRecord(slog.LevelInfo, "probe result", "scenario", scenario, "target", target, "status", r.Result,
+		"http_status", r.statusCode, "tls_version", r.tlsVersion, "handshake_ms", r.HandshakeMS,
+		"leaf_not_after", r.leafNotAfter)
+}
+
+func addResult(r probeResult) {
+	resultsMu.Lock()
+	results = append(results, r)
+	resultsMu.Unlock()
+}
+
+// sortResults puts one target's results back in scenario order after the
+// concurrent scenarios recorded them in whatever order they finished.
+func sortResults(recorded []probeResult) {
+	order := map[string]int{"service-account-ca": 0, "system-and-service-account-ca": 1, "system-only": 2, "insecure": 3}
+	sort.SliceStable(recorded, func(i, j int) bool {
+		return order[recorded[i].Scenario] < order[recorded[j].Scenario]
+	})
+}
+
+// writeResultsConfigMap creates or updates a ConfigMap with the probe
+// results, so a controller or dashboard can read the latest audit without
+// scraping Job logs.
+func writeResultsConfigMap(ctx context.Context, ref, target string) error {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return fmt.Errorf("expected namespace/name, got %q", ref)
+	}
+
+	resultsJSON, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	status := "success"
+	for _, r := range results {
+		if r.Result == "fail" {
+			status = "fail"
+		}
+	}
+	if latencyExceeded.Load() {
+		status = "fail"
+	}
+
+	data := map[string]interface{}{
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+		"cryptoBackend": cryptoBackend(),
+		"targets":       target,
+		"status":        status,
+		"results.json":  string(resultsJSON),
+	}
+	const label, labelValue = "app.kubernetes.io/name", "tls-trust-audit"
+
+	// Only this run's keys and label are replaced; anything else on an
+	// existing ConfigMap is kept. The update carries the resourceVersion
+	// that was read, so when concurrent audit Jobs race, the loser re-reads
+	// and merges again instead of overwriting the winner.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var existing map[string]interface{}
+		err := kubeGet(ctx, configMapsResource, namespace, name, &existing)
+		if errors.Is(err, errKubeNotFound) {
+			cm := map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": namespace,
+					"labels":    map[string]interface{}{label: labelValue},
+				},
+				"data": data,
+			}
+			err = kubeCreate(ctx, configMapsResource, namespace, cm)
+			if apierrors.IsAlreadyExists(err) {
+				// Created concurrently: retry as an update
+				return apierrors.NewConflict(configMapsResource.GroupResource(), name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		for key, value := range data {
+			if err := unstructured.SetNestedField(existing, value, "data", key); err != nil {
+				return fmt.Errorf("cannot set data.%s on ConfigMap %s: %v", key, ref, err)
+			}
+		}
+		if err := unstructured.SetNestedField(existing, labelValue, "metadata", "labels", label); err != nil {
+			return fmt.Errorf("cannot label ConfigMap %s: %v", ref, err)
+		}
+		return kubeUpdate(ctx, configMapsResource, namespace, existing)
+	})
+}
+
+// resultFor returns the recorded outcome of a probe scenario against
+// target, or "" if it did not run.
+func resultFor(scenario, target string) string {
+	for _, r := range results {
+		if r.Scenario == scenario && r.Target == target {
+			return r.Result
+		}
+	}
+	return ""
+}
+
+// writeMetricsFile writes the probe results in the Prometheus text format
+// for the node_exporter textfile collector. The file is written under a
+// temporary name and renamed into place so the collector never reads it
+// half-written. Skipped scenarios have no series: they say nothing about
+// the endpoint.
+func writeMetricsFile(path string) error {
+	label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
+	var b bytes.Buffer
+	metric := func(name, help string, value func(r probeResult) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, r := range results {
+			if v, ok := value(r); ok {
+				fmt.Fprintf(&b, "%s{target=\"%s\",scenario=\"%s\",tls_version=\"%s\"} %s\n",
+					name, label(r.Target), label(r.Scenario), label(r.tlsVersion), strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+	metric("tls_probe_success", "Whether the scenario's request succeeded with a verified TLS connection.",
+		func(r probeResult) (float64, bool) {
+			if r.Result == "skipped" {
+				return 0, false
+			}
+			if r.Result == "success" {
+				return 1, true
+			}
+			return 0, true
+		})
+	metric("tls_probe_handshake_seconds", "Duration of the scenario's TLS handshake.",
+		func(r probeResult) (float64, bool) {
+			return float64(r.HandshakeMS) / 1000, r.Result == "success"
+		})
+	metric("tls_probe_cert_expiry_seconds", "Unix time at which the peer's leaf certificate expires.",
+		func(r probeResult) (float64, bool) {
+			return float64(r.leafNotAfter.Unix()), !r.leafNotAfter.IsZero()
+		})
+	fmt.Fprintf(&b, "# HELP tls_probe_last_run_timestamp_seconds Unix time the probe last ran.\n")
+	fmt.Fprintf(&b, "# TYPE tls_probe_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "tls_probe_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tls_probe_*.prom")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// printRemediation turns the probe results into a concrete patch:
+// enabling --use-system-trust-store on the proxy when only the system
+// roots are missing, or appending the server's root to a CA bundle
+// ConfigMap when no trust store knows it.
+func printRemediation(ctx context.Context, tokenURL, deployment, container, trustConfigMap string) {
+	fmt.Fprintln(out, "--- Remediation ---")
+
+	switch {
+	case resultFor("service-account-ca", tokenURL) == "success":
+		fmt.Fprintln(out, "✅ Nothing to remediate: the default configuration already trusts the endpoint")
+	case resultFor("system-and-service-account-ca", tokenURL) == "success":
+		fmt.Fprintln(out, "Diagnosis: the endpoint is trusted only with the system trust store enabled")
+		if err := printTrustStoreFlagPatch(ctx, deployment, container); err != nil {
+			fmt.Fprintf(warnOut, "⚠️  Cannot generate patch: %v\n", err)
+		}
+	case resultFor("system-and-service-account-ca", tokenURL) == "fail":
+		fmt.Fprintln(out, "Diagnosis: no trust store knows the endpoint's root")
+		if err := printAppendRootPatch(ctx, tokenURL, trustConfigMap); err != nil {
+			fmt.Fprintf(warnOut, "⚠️  Cannot generate patch: %v\n", err)
+		}
+	default:
+		fmt.Fprintln(warnOut, "⚠️  The system trust store probe was skipped; cannot diagnose a fix")
+	}
+}
+
+// printTrustStoreFlagPatch prints a strategic merge patch adding
+// --use-system-trust-store=true to the proxy container. Strategic merge
+// replaces args wholesale, so the existing args are carried over.
+func printTrustStoreFlagPatch(ctx context.Context, deployment, container string) error {
+	namespace, name, ok := strings.Cut(deployment, "/")
+	if !ok || namespace == "" || name == "" {
+		return fmt.Errorf("expected namespace/name, got %q", deployment)
+	}
+	var d struct {
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Name string   `json:"name"`
+						Args []string `json:"args"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := kubeGet(ctx, deploymentsResource, namespace, name, &d); err != nil {
+		return err
+	}
+
+	var args []string
+	found := false
+	for _, c := range d.Spec.Template.Spec.Containers {
+		if c.Name == container {
+			args, found = c.Args, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("deployment %s has no container %q", deployment, container)
+	}
+
+	var patched []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--use-system-trust-store") {
+			patched = append(patched, arg)
+		}
+	}
+	patched = append(patched, "--use-system-trust-store=true")
+
+	fmt.Fprintln(out, "Fix: enable --use-system-trust-store on kube-auth-proxy")
+	fmt.Fprintf(out, "Review, save and apply with: kubectl -n %s patch deployment %s --patch-file <file>\n\n", namespace, name)
+	fmt.Fprintln(out, "spec:")
+	fmt.Fprintln(out, "  template:")
+	fmt.Fprintln(out, "    spec:")
+	fmt.Fprintln(out, "      containers:")
+	fmt.Fprintf(out, "      - name: %q\n", container)
+	fmt.Fprintln(out, "        args:")
+	for _, arg := range patched {
+		fmt.Fprintf(out, "        - %q\n", arg)
+	}
+	return nil
+}
+
+// printAppendRootPatch prints a merge patch that appends the root of the
+// chain the server presents to the given CA bundle ConfigMap. Only a root
+// sent by the server itself can be appended; otherwise the issuer to
+// obtain is named instead.
+func printAppendRootPatch(ctx context.Context, tokenURL, trustConfigMap string) error {
+	chain, err := captureHandshakeChain(ctx, tokenURL)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("server presented no certificates")
+	}
+	top := chain[len(chain)-1]
+	if !bytes.Equal(top.RawSubject, top.RawIssuer) {
+		fmt.Fprintln(out, "Fix: add the root CA below to the trusted bundle")
+		fmt.Fprintf(out, "   The server does not send its root; obtain the certificate for: %s\n", top.Issuer.String())
+		return nil
+	}
+	if trustConfigMap == "" {
+		return fmt.Errorf("set -trust-configmap to the bundle ConfigMap the root %s should be added to", top.Subject.String())
+	}
+
+	cm, err := getConfigMap(ctx, trustConfigMap)
+	if err != nil {
+		return err
+	}
+	const key = "ca-bundle.crt"
+	bundle := strings.TrimRight(cm.Data[key], "\n")
+	if bundle != "" {
+		bundle += "\n"
+	}
+	bundle += string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: top.Raw}))
+
+	namespace, name, _ := strings.Cut(trustConfigMap, "/")
+	fmt.Fprintf(out, "Fix: append root %s to %s key %s\n", top.Subject.String(), trustConfigMap, key)
+	fmt.Fprintf(out, "Review, save and apply with: kubectl -n %s patch configmap %s --type merge --patch-file <file>\n\n", namespace, name)
+	fmt.Fprintln(out, "data:")
+	fmt.Fprintf(out, "  %s: |\n", key)
+	for _, line := range strings.Split(strings.TrimRight(bundle, "\n"), "\n") {
+		fmt.Fprintf(out, "    %s\n", line)
+	}
+	return nil
+}
+
+// cryptoBackend reports which crypto implementation this binary was built
+// with: BoringCrypto (GOEXPERIMENT=boringcrypto), Go's native FIPS 140-3
+// module, or the standard Go implementation.
+func cryptoBackend() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "GOEXPERIMENT" && strings.Contains(s.Value, "boringcrypto") {
+				return "BoringCrypto (FIPS)"
+			}
+		}
+	}
+	if fips140.Enabled() {
+		return "Go FIPS 140-3 module"
+	}
+	return "Go standard crypto"
+}
+
+// compareDiscovery diffs the live discovery document against a golden one
+// field by field and reports every difference not in the allow list.
+func compareDiscovery(live []byte, goldenPath, allowList string) bool {
+	fmt.Fprintf(out, "--- Discovery Comparison: %s ---\n", goldenPath)
+
+	goldenData, err := os.ReadFile(goldenPath)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read golden discovery document %s: %v\n\n", goldenPath, err)
+		return false
+	}
+	var golden, actual map[string]interface{}
+	if err := json.Unmarshal(goldenData, &golden); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot parse golden discovery document: %v\n\n", err)
+		return false
+	}
+	if err := json.Unmarshal(live, &actual); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot parse live discovery document: %v\n\n", err)
+		return false
+	}
+
+	allowed := map[string]bool{}
+	for _, field := range strings.Split(allowList, ",") {
+		allowed[strings.TrimSpace(field)] = true
+	}
+
+	fields := map[string]bool{}
+	for k := range golden {
+		fields[k] = true
+	}
+	for k := range actual {
+		fields[k] = true
+	}
+	var names []string
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	ok := true
+	differences := 0
+	for _, field := range names {
+		want, inGolden := golden[field]
+		got, inLive := actual[field]
+		if inGolden && inLive && reflect.DeepEqual(want, got) {
+			continue
+		}
+		differences++
+		marker, w := "❌", errOut
+		if allowed[field] {
+			marker, w = "ℹ️  (allowed)", out
+		} else {
+			ok = false
+		}
+		switch {
+		case !inLive:
+			fmt.Fprintf(w, "%s %s: missing from live document (expected %s)\n", marker, field, jsonValue(want))
+		case !inGolden:
+			fmt.Fprintf(w, "%s %s: not in golden document (live %s)\n", marker, field, jsonValue(got))
+		default:
+			fmt.Fprintf(w, "%s %s: expected %s, got %s\n", marker, field, jsonValue(want), jsonValue(got))
+		}
+	}
+
+	if differences == 0 {
+		fmt.Fprintln(out, "✅ Live discovery document matches the golden document")
+	} else if ok {
+		fmt.Fprintf(out, "✅ %d differing fields, all allowed\n", differences)
+	}
+	fmt.Fprintln(out)
+	return ok
+}
+
+// jsonValue renders a decoded JSON value compactly for reporting.
+func jsonValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// checkResolvesWithin resolves the URL's host and reports whether any of its
+// addresses fall inside the expected network, as a guard against DNS
+// poisoning or a route pointing somewhere unexpected.
+func checkResolvesWithin(ctx context.Context, rawURL string, expected *net.IPNet) bool {
+	fmt.Fprintf(out, "--- DNS Check: expecting %s ---\n", expected)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot parse URL: %v\n\n", err)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot resolve %s: %v\n\n", u.Hostname(), err)
+		return false
+	}
+
+	matched := false
+	for _, addr := range addrs {
+		if expected.Contains(addr.IP) {
+			fmt.Fprintf(out, "   ✅ %s\n", addr.IP)
+			matched = true
+		} else {
+			fmt.Fprintf(errOut, "   ❌ %s (outside %s)\n", addr.IP, expected)
+		}
+	}
+
+	if !matched {
+		fmt.Fprintf(errOut, "❌ FAIL: %s does not resolve to any address in %s\n\n", u.Hostname(), expected)
+		return false
+	}
+	fmt.Fprintf(out, "✅ %s resolves within %s\n\n", u.Hostname(), expected)
+	return true
+}
+
+// probeTimeout returns the per-scenario override when set, falling back to
+// the global -timeout, and reports which one applied.
+func probeTimeout(w io.Writer, override time.Duration, flagName string) time.Duration {
+	if override > 0 {
+		fmt.Fprintf(at(w, slog.LevelInfo), "(Timeout: %s from -%s)\n", override, flagName)
+		return override
+	}
+	fmt.Fprintf(at(w, slog.LevelInfo), "(Timeout: %s from -timeout)\n", timeout)
+	return timeout
+}
+
+// loadSystemCertPool returns the platform trust store. When the platform
+// cannot provide one it falls back to -system-ca-fallback if set, and
+// otherwise returns an error wrapping errSystemTrustUnavailable so callers
+// skip the scenario instead of testing against an empty pool. The fallback
+// warning goes to w, the calling scenario's output.
+func loadSystemCertPool(w io.Writer) (*x509.CertPool, error) {
+	pool, err := systemCertPoolLoader()
+	if err == nil {
+		return pool, nil
+	}
+
+	if systemCAFallback == "" {
+		return nil, fmt.Errorf("%w (%s/%s: %v); skipping - set -system-ca-fallback to use a PEM bundle instead",
+			errSystemTrustUnavailable, runtime.GOOS, runtime.GOARCH, err)
+	}
+
+	fmt.Fprintf(at(w, slog.LevelWarn), "⚠️  WARNING: Cannot load system cert pool (%v); using fallback %s\n", err, systemCAFallback)
+	fallbackPEM, readErr := os.ReadFile(systemCAFallback)
+	if readErr != nil {
+		return nil, fmt.Errorf("%w: cannot read fallback bundle %s: %v", errSystemTrustUnavailable, systemCAFallback, readErr)
+	}
+	pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(fallbackPEM) {
+		return nil, fmt.Errorf("%w: fallback bundle %s contains no certificates", errSystemTrustUnavailable, systemCAFallback)
+	}
+	return pool, nil
+}
+
+// minSystemRoots is the fewest roots a real distro trust store is expected
+// to hold; the Mozilla-derived bundles carry well over a hundred.
+const minSystemRoots = 20
+
+// warnIfSystemPoolSparse warns when the platform trust store loads but is
+// empty or nearly so, as in scratch and distroless images without
+// ca-certificates. x509.SystemCertPool succeeds there, so without this the
+// failures that follow read as "the needed root is missing" rather than
+// "there are no roots at all". It checks the platform store itself, not a
+// -system-ca-fallback bundle, and is silent where the store is unavailable
+// (already reported) or, on macOS and Windows, cannot be enumerated.
+func warnIfSystemPoolSparse(w io.Writer) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" || runtime.GOOS == "windows" {
+		return
+	}
+	pool, err := systemCertPoolLoader()
+	if err != nil {
+		return
+	}
+	// Subjects is deprecated only for the platform-verifier pools skipped above
+	n := len(pool.Subjects())
+	switch {
+	case n == 0:
+		fmt.Fprintln(at(w, slog.LevelWarn), "⚠️  WARNING: System trust store is EMPTY (0 certificates)")
+	case n < minSystemRoots:
+		fmt.Fprintf(at(w, slog.LevelWarn), "⚠️  WARNING: System trust store holds only %d certificates (expected %d or more)\n", n, minSystemRoots)
+	default:
+		return
+	}
+	fmt.Fprintln(at(w, slog.LevelWarn), "   → The image likely lacks the ca-certificates package; that, not a missing root, is the root cause of system-trust failures")
+	fmt.Fprintf(at(w, slog.LevelWarn), "   → Install ca-certificates, or point SSL_CERT_FILE at a bundle such as %s\n", systemBundlePaths[0])
+}
+
+// newProbeTransport builds the HTTP transport shared by the probe
+// scenarios, applying the connection options selected on the command line.
+func newProbeTransport(tlsConfig *tls.Config) *http.Transport {
+	applyClientHelloProfile(tlsConfig)
+	if clockOffset != 0 {
+		tlsConfig.Time = validationTime
+	}
+	if strictHostname {
+		tlsConfig.VerifyConnection = verifyStrictHostname
+	}
+	if clientCertificate != nil {
+		// Supplied through the callback rather than Certificates so the
+		// probe can tell whether the server actually asked for it
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			clientCertRequested.Store(true)
+			return clientCertificate, nil
+		}
+	}
+	transport := &http.Transport{
+		Proxy:           proxyForRequest,
+		TLSClientConfig: tlsConfig,
+	}
+	if noKeepAlive {
+		transport.DisableKeepAlives = true
+		// MaxIdleConns 0 means "no limit", so disable idle pooling through
+		// the per-host limit instead
+		transport.MaxIdleConns = 0
+		transport.MaxIdleConnsPerHost = -1
+	}
+	return transport
+}
+
+// verifyStrictHostname rejects a connection to a DNS-named server unless
+// the leaf has a DNS SAN matching it. It runs in addition to normal
+// verification, and explains which weaker match the cert relied on.
+func verifyStrictHostname(cs tls.ConnectionState) error {
+	if net.ParseIP(cs.ServerName) != nil || len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := cs.PeerCertificates[0]
+	for _, name := range leaf.DNSNames {
+		if matchDNSName(name, cs.ServerName) {
+			return nil
+		}
+	}
+	switch {
+	case len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) > 0:
+		return fmt.Errorf("strict hostname: certificate has only IP SANs %v, which cannot match DNS name %s", leaf.IPAddresses, cs.ServerName)
+	case len(leaf.DNSNames) == 0:
+		return fmt.Errorf("strict hostname: certificate has no DNS SANs; CN %q is never used to match %s", leaf.Subject.CommonName, cs.ServerName)
+	default:
+		return fmt.Errorf("strict hostname: no DNS SAN in %v matches %s", leaf.DNSNames, cs.ServerName)
+	}
+}
+
+// matchDNSName matches a SAN against a hostname, allowing a wildcard only
+// as the whole leftmost label.
+func matchDNSName(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !strings.HasPrefix(pattern, "*.") {
+		return pattern == host
+	}
+	_, rest, ok := strings.Cut(host, ".")
+	return ok && rest == pattern[2:]
+}
+
+// prober runs the trust-store probes with the connection options selected
+// on the command line.
+var prober = &tlsprobe.Prober{Transport: newProbeTransport}
+
+// printProbeSuccess formats a successful probe result.
+func printProbeSuccess(w io.Writer, target string, result *tlsprobe.ProbeResult, explanation string) {
+	fmt.Fprintf(at(w, slog.LevelInfo), "✅ SUCCESS: HTTP %d\n", result.StatusCode)
+	fmt.Fprintf(at(w, slog.LevelInfo), "   → %s\n", explanation)
+	fmt.Fprintf(at(w, slog.LevelInfo), "   TLS version: %s\n", result.TLSVersionName())
+	fmt.Fprintf(at(w, slog.LevelInfo), "   Cipher suite: %s\n", result.CipherSuiteName())
+	if len(result.PeerCertificates) > 0 {
+		leaf := result.PeerCertificates[0]
+		fmt.Fprintf(at(w, slog.LevelInfo), "   Server cert: %s (%d certificates in chain)\n", leaf.Subject.String(), len(result.PeerCertificates))
+		fmt.Fprintf(at(w, slog.LevelInfo), "   Server CN: %s\n", leaf.Subject.CommonName)
+		checkHostnameMatch(w, target, leaf)
+	}
+	if clientCertificate != nil {
+		if clientCertRequested.Load() {
+			fmt.Fprintln(at(w, slog.LevelInfo), "   Client cert: presented (server requested it; mutual TLS engaged)")
+		} else {
+			fmt.Fprintln(at(w, slog.LevelInfo), "   Client cert: not presented (server did not request one)")
+		}
+	}
+	checkHandshakeLatency(w, result.Handshake)
+}
+
+// checkHostnameMatch confirms the target's host is covered by the leaf's
+// SANs. The handshake already checked this, so a mismatch here means the
+// check was bypassed or weakened somewhere and deserves a loud report.
+func checkHostnameMatch(w io.Writer, target string, leaf *x509.Certificate) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return
+	}
+	host := u.Hostname()
+	if err := leaf.VerifyHostname(host); err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "   ❌ Hostname mismatch: %v\n", err)
+		return
+	}
+	if len(leaf.DNSNames) > 0 {
+		fmt.Fprintf(at(w, slog.LevelInfo), "   Hostname: %s matches SANs %v\n", host, leaf.DNSNames)
+	} else {
+		fmt.Fprintf(at(w, slog.LevelInfo), "   Hostname: %s matches the certificate's IP SANs\n", host)
+	}
+}
+
+// printProbeFailure explains a failed probe. A certificate whose SANs do
+// not cover the host is reported separately from an untrusted chain; the
+// two otherwise look identical and need different fixes.
+func printProbeFailure(w io.Writer, err error, trustExplanation string) {
+	var hostErr x509.HostnameError
+	if errors.As(err, &hostErr) {
+		fmt.Fprintf(at(w, slog.LevelError), "   → Hostname mismatch: the certificate does not cover %s (SANs: %v)\n", hostErr.Host, hostErr.Certificate.DNSNames)
+		fmt.Fprintln(at(w, slog.LevelError), "   → This is a SAN problem, separate from CA trust: reissue the certificate with the right SAN or fix the URL")
+		return
+	}
+	fmt.Fprintf(at(w, slog.LevelError), "   → %s\n", trustExplanation)
+}
+
+// checkHandshakeLatency reports the measured handshake time and records a
+// failure when it exceeds -max-handshake-latency.
+func checkHandshakeLatency(w io.Writer, handshake time.Duration) {
+	if maxHandshakeLatency == 0 {
+		fmt.Fprintf(at(w, slog.LevelInfo), "   Handshake: %s\n", handshake.Round(time.Millisecond))
+		return
+	}
+	if handshake > maxHandshakeLatency {
+		fmt.Fprintf(at(w, slog.LevelError), "   ❌ Handshake: %s (exceeds max %s)\n", handshake.Round(time.Millisecond), maxHandshakeLatency)
+		latencyExceeded.Store(true)
+		return
+	}
+	fmt.Fprintf(at(w, slog.LevelInfo), "   ✅ Handshake: %s (within max %s)\n", handshake.Round(time.Millisecond), maxHandshakeLatency)
+}
+
+func testWithServiceAccountCA(ctx context.Context, w io.Writer, url string) {
+	// Load service account CA
+	caPEM, err := readServiceAccountCA()
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: Cannot read service account CA: %v\n", err)
+		recordResult("service-account-ca", url, "fail", "cannot read service account CA: "+err.Error(), 0)
+		return
+	}
+
+	// Create cert pool with only service account CA
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caPEM) {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: Cannot parse service account CA\n")
+		recordResult("service-account-ca", url, "fail", "cannot parse service account CA", 0)
+		return
+	}
+
+	result, err := prober.ProbeWithCAPoolContext(ctx, url, certPool)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: %v\n", err)
+		recordResult("service-account-ca", url, "fail", err.Error(), 0)
+		printProbeFailure(w, err, "TLS validation failed with service account CA only")
+		dumpChainOnVerifyError(ctx, w, url, err)
+		return
+	}
+
+	printProbeSuccess(w, url, result, "TLS validation succeeded (certificate trusted via service account CA)")
+	recordSuccess("service-account-ca", url, result)
+}
+
+func testWithSystemTrustStore(ctx context.Context, w io.Writer, url string) {
+	// Load system cert pool first
+	certPool, err := loadSystemCertPool(w)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelWarn), "⚠️  SKIPPED: %v\n", err)
+		recordResult("system-and-service-account-ca", url, "skipped", err.Error(), 0)
+		return
+	}
+	warnIfSystemPoolSparse(w)
+
+	// Add service account CA on top
+	caPEM, err := readServiceAccountCA()
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: Cannot read service account CA: %v\n", err)
+		recordResult("system-and-service-account-ca", url, "fail", "cannot read service account CA: "+err.Error(), 0)
+		return
+	}
+
+	if !certPool.AppendCertsFromPEM(caPEM) {
+		fmt.Fprintf(at(w, slog.LevelWarn), "⚠️  WARNING: Cannot parse service account CA\n")
+	}
+
+	result, err := prober.ProbeWithCAPoolContext(ctx, url, certPool)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: %v\n", err)
+		recordResult("system-and-service-account-ca", url, "fail", err.Error(), 0)
+		printProbeFailure(w, err, "TLS validation failed even with system trust store")
+		dumpChainOnVerifyError(ctx, w, url, err)
+		return
+	}
+
+	printProbeSuccess(w, url, result, "TLS validation succeeded (system CAs + service account CA)")
+	recordSuccess("system-and-service-account-ca", url, result)
+}
+
+func testWithSystemOnly(ctx context.Context, w io.Writer, url string) {
+	// Use system cert pool only
+	certPool, err := loadSystemCertPool(w)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelWarn), "⚠️  SKIPPED: %v\n", err)
+		recordResult("system-only", url, "skipped", err.Error(), 0)
+		return
+	}
+	warnIfSystemPoolSparse(w)
+
+	result, err := prober.ProbeWithCAPoolContext(ctx, url, certPool)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL: %v\n", err)
+		recordResult("system-only", url, "fail", err.Error(), 0)
+		printProbeFailure(w, err, "TLS validation failed with system trust store only")
+		dumpChainOnVerifyError(ctx, w, url, err)
+		return
+	}
+
+	printProbeSuccess(w, url, result, "TLS validation succeeded (system CAs only)")
+	recordSuccess("system-only", url, result)
+}
+
+// testInsecure connects with certificate verification disabled, as curl -k
+// would. A success shows the endpoint is reachable and speaks TLS, so a
+// failure in the other scenarios is about trust; a failure here points at
+// the network instead. The result must never be read as the endpoint being
+// trusted.
+func testInsecure(ctx context.Context, w io.Writer, url string) {
+	fmt.Fprintln(at(w, slog.LevelWarn), "⚠️  INSECURE: certificate verification is DISABLED for this test; it does not validate the server's certificate")
+	insecureProber := &tlsprobe.Prober{Transport: func(cfg *tls.Config) *http.Transport {
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = nil
+		return newProbeTransport(cfg)
+	}}
+	result, err := insecureProber.ProbeWithCAPoolContext(ctx, url, nil)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelError), "❌ FAIL even without verification: %v\n", err)
+		fmt.Fprintln(at(w, slog.LevelError), "   → Not a trust problem: check connectivity, proxies, firewalls and TLS protocol support")
+		recordResult("insecure", url, "fail", err.Error(), 0)
+		return
+	}
+
+	fmt.Fprintf(at(w, slog.LevelInfo), "✅ CONNECTED (NOT VERIFIED): HTTP %d\n", result.StatusCode)
+	fmt.Fprintf(at(w, slog.LevelInfo), "   TLS version: %s\n", result.TLSVersionName())
+	fmt.Fprintf(at(w, slog.LevelInfo), "   Cipher suite: %s\n", result.CipherSuiteName())
+	fmt.Fprintf(at(w, slog.LevelInfo), "   Peer chain (%d certificates, NOT verified):\n", len(result.PeerCertificates))
+	for i, cert := range result.PeerCertificates {
+		fmt.Fprintf(at(w, slog.LevelInfo), "     %d. %s\n", i+1, cert.Subject.String())
+		fmt.Fprintf(at(w, slog.LevelInfo), "        issued by %s\n", cert.Issuer.String())
+	}
+	recordSuccess("insecure", url, result)
+}
+
+// dumpChainOnVerifyError, with -dump-peer-chain, captures the chain served
+// by a target whose certificate failed verification and prints it as PEM.
+// The capture is a second handshake with verification disabled, so it is
+// only fit for diagnostics.
+func dumpChainOnVerifyError(ctx context.Context, w io.Writer, url string, err error) {
+	var verifyErr *tls.CertificateVerificationError
+	if !dumpPeerChain || !errors.As(err, &verifyErr) {
+		return
+	}
+	dumpedChainsMu.Lock()
+	dumped := dumpedChains[url]
+	dumpedChains[url] = true
+	dumpedChainsMu.Unlock()
+	if dumped {
+		return
+	}
+
+	chain, err := captureHandshakeChain(ctx, url)
+	if err != nil {
+		fmt.Fprintf(at(w, slog.LevelWarn), "   ⚠️  Cannot capture peer chain: %v\n", err)
+		return
+	}
+	fmt.Fprintf(at(w, slog.LevelWarn), "   ⚠️  INSECURE capture for diagnostics only: %d certificates served by %s, NOT verified\n", len(chain), url)
+	var chainPEM bytes.Buffer
+	for _, cert := range chain {
+		pem.Encode(&chainPEM, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	if logger != nil {
+		logRecord(slog.LevelWarn, "peer chain", "target", url, "certificates", len(chain), "pem", chainPEM.String())
+	} else {
+		at(w, slog.LevelWarn).Write(chainPEM.Bytes())
+	}
+	fmt.Fprintln(at(w, slog.LevelWarn), "   → Save the PEM above to a file and run: go run ./cmd/verify-root-ca <file>")
+}
+
+// checkPKCEMethods reports the advertised PKCE code challenge methods and
+// flags a server without S256, which breaks PKCE logins for public clients.
+func checkPKCEMethods(methods []string) {
+	if len(methods) == 0 {
+		fmt.Fprintln(warnOut, "   ⚠️  PKCE: no code_challenge_methods_supported advertised - S256 PKCE logins may fail")
+		return
+	}
+	fmt.Fprintf(out, "   PKCE methods: %s\n", strings.Join(methods, ", "))
+	for _, m := range methods {
+		if m == "S256" {
+			return
+		}
+	}
+	fmt.Fprintln(warnOut, "   ⚠️  PKCE: S256 not advertised - S256 PKCE logins will fail")
+}
+
+// checkServiceAccountToken makes a minimal authenticated call to /api and
+// reports whether the service account token is valid, expired or lacks
+// RBAC, before discovery conflates those with TLS failures.
+func checkServiceAccountToken(ctx context.Context) bool {
+	fmt.Fprintln(out, "--- Service Account Token Preflight ---")
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	api, err := loadKubeAPI()
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: %v\n\n", err)
+		return false
+	}
+	client, req, err := newKubeAPIRequest(reqCtx, "GET", "/api", nil)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: %v\n\n", err)
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: GET /api failed: %v\n", err)
+		fmt.Fprintln(errOut, "   → Cannot reach the API server; this is a connectivity or TLS problem, not an auth problem")
+		fmt.Fprintln(out)
+		return false
+	}
+	resp.Body.Close()
+
+	ok := true
+	switch resp.StatusCode {
+	case http.StatusOK:
+		fmt.Fprintln(out, "✅ Token valid")
+	case http.StatusUnauthorized:
+		if exp, err := tokenExpiry(api.token); err == nil && time.Now().After(exp) {
+			fmt.Fprintf(errOut, "❌ FAIL: Token expired at %s\n", exp.Format(time.RFC3339))
+		} else {
+			fmt.Fprintln(errOut, "❌ FAIL: Token rejected (HTTP 401) - revoked, for a deleted service account, or from another cluster")
+		}
+		ok = false
+	case http.StatusForbidden:
+		fmt.Fprintln(errOut, "❌ FAIL: Token forbidden (HTTP 403) - authenticated but lacks RBAC for API discovery")
+		ok = false
+	default:
+		fmt.Fprintf(warnOut, "⚠️  Unexpected HTTP %d from /api; continuing with discovery\n", resp.StatusCode)
+	}
+	fmt.Fprintln(out)
+	return ok
+}
+
+// tokenClaims are the JWT claims read from a service account token.
+type tokenClaims struct {
+	Exp int64  `json:"exp"`
+	Iss string `json:"iss"`
+	Sub string `json:"sub"`
+}
+
+// decodeTokenClaims reads the claims from a bearer JWT without verifying it.
+func decodeTokenClaims(authorization string) (*tokenClaims, error) {
+	parts := strings.Split(strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// printTokenClaims reports the claims of the discovery bearer token, so a
+// 401 can be told apart as an expired or wrongly scoped token. The claims
+// are decoded without verifying the signature, and the token is redacted.
+func printTokenClaims(token string) {
+	if token == "" {
+		fmt.Fprintln(out, "Bearer token: none up front (client certificate, exec plugin or auth provider authentication)")
+		return
+	}
+	fmt.Fprintf(out, "Bearer token: [REDACTED, %d bytes]\n", len(token))
+	claims, err := decodeTokenClaims(token)
+	if err != nil {
+		fmt.Fprintf(warnOut, "   ⚠️  Cannot decode token claims: %v (opaque tokens, such as OpenShift sha256~ tokens, carry none)\n", err)
+		return
+	}
+	fmt.Fprintf(out, "   iss: %s\n", claims.Iss)
+	fmt.Fprintf(out, "   sub: %s\n", claims.Sub)
+	if rest, ok := strings.CutPrefix(claims.Sub, "system:serviceaccount:"); ok {
+		if namespace, name, ok := strings.Cut(rest, ":"); ok {
+			fmt.Fprintf(out, "   Service account: %s (namespace %s)\n", name, namespace)
+		}
+	}
+	if claims.Exp == 0 {
+		fmt.Fprintln(out, "   exp: none (a legacy long-lived secret token)")
+		return
+	}
+	exp := time.Unix(claims.Exp, 0)
+	if remaining := time.Until(exp); remaining > 0 {
+		fmt.Fprintf(out, "   exp: %s (in %s)\n", exp.UTC().Format(time.RFC3339), remaining.Round(time.Second))
+	} else {
+		fmt.Fprintf(errOut, "   ❌ exp: %s (expired %s ago)\n", exp.UTC().Format(time.RFC3339), (-remaining).Round(time.Second))
+		fmt.Fprintln(errOut, "   → The projected token has expired; discovery will fail with HTTP 401")
+	}
+}
+
+// tokenExpiry reads the exp claim from a bearer JWT without verifying it.
+func tokenExpiry(authorization string) (time.Time, error) {
+	claims, err := decodeTokenClaims(authorization)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("token has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// checkServiceAccountFiles is the preflight mode: it confirms the pod's
+// service account was projected correctly, without contacting the API
+// server, so a missing or broken mount is not mistaken for a TLS or
+// discovery problem.
+func checkServiceAccountFiles() bool {
+	fmt.Fprintln(out, "=== Service Account Preflight ===")
+	fmt.Fprintln(out)
+
+	ok := true
+	fmt.Fprintf(out, "--- CA: %s ---\n", serviceAccountCAPath)
+	if caPEM, fileOK := checkServiceAccountFileReadable(serviceAccountCAPath, "ca-path", "SA_CA_PATH"); fileOK {
+		certs := parsePEMCertificates(caPEM)
+		if len(certs) == 0 {
+			fmt.Fprintln(errOut, "❌ Contains no valid PEM certificates")
+			ok = false
+		} else {
+			fmt.Fprintf(out, "✅ Contains %d certificates\n", len(certs))
+			for _, cert := range certs {
+				fmt.Fprintf(out, "   %s (expires %s)\n", cert.Subject.String(), cert.NotAfter.Format("2006-01-02"))
+			}
+		}
+	} else {
+		ok = false
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintf(out, "--- Token: %s ---\n", serviceAccountTokenPath)
+	if token, fileOK := checkServiceAccountFileReadable(serviceAccountTokenPath, "token-path", "SA_TOKEN_PATH"); fileOK {
+		claims, err := decodeTokenClaims(string(token))
+		switch {
+		case err != nil:
+			fmt.Fprintf(errOut, "❌ Cannot decode token as a JWT: %v\n", err)
+			ok = false
+		case claims.Exp == 0:
+			fmt.Fprintf(out, "✅ JWT for %s\n", claims.Sub)
+			fmt.Fprintln(warnOut, "⚠️  No expiry: a legacy long-lived secret token rather than a projected one")
+		default:
+			exp := time.Unix(claims.Exp, 0)
+			fmt.Fprintf(out, "✅ JWT for %s\n", claims.Sub)
+			if remaining := time.Until(exp); remaining > 0 {
+				fmt.Fprintf(out, "✅ Expires %s (in %s)\n", exp.UTC().Format(time.RFC3339), remaining.Round(time.Second))
+			} else {
+				fmt.Fprintf(errOut, "❌ Expired at %s (%s ago)\n", exp.UTC().Format(time.RFC3339), (-remaining).Round(time.Second))
+				fmt.Fprintln(errOut, "   → The kubelet refreshes projected tokens; an expired one means the pod is not picking up the refreshed file")
+				ok = false
+			}
+		}
+	} else {
+		ok = false
+	}
+	fmt.Fprintln(out)
+
+	if ok {
+		fmt.Fprintln(out, "✅ PASS: service account files are usable")
+	} else {
+		fmt.Fprintln(errOut, "❌ FAIL: service account files are missing or invalid")
+	}
+	return ok
+}
+
+// checkServiceAccountFileReadable reports whether a service account file
+// exists, is readable and is not empty, and returns its contents.
+func checkServiceAccountFileReadable(path, flagName, envName string) ([]byte, bool) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintf(errOut, "❌ Does not exist (not running in a pod? override with -%s or %s)\n", flagName, envName)
+		} else {
+			fmt.Fprintf(errOut, "❌ Cannot stat: %v\n", err)
+		}
+		return nil, false
+	}
+	fmt.Fprintln(out, "✅ Exists")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ Not readable: %v\n", err)
+		return nil, false
+	}
+	fmt.Fprintln(out, "✅ Readable")
+	if len(bytes.TrimSpace(data)) == 0 {
+		fmt.Fprintln(errOut, "❌ Empty")
+		return nil, false
+	}
+	fmt.Fprintf(out, "✅ Not empty (%d bytes)\n", len(data))
+	return data, true
+}
+
+// discoverOAuthURL returns the token endpoint from the OAuth discovery
+// document, along with the raw document.
+func discoverOAuthURL(ctx context.Context) (string, []byte, error) {
+	fmt.Fprintln(out, "--- OAuth Discovery from Kubernetes API ---")
+	api, err := loadKubeAPI()
+	if err != nil {
+		return "", nil, err
+	}
+	discoveryURL := api.server + oauthDiscoveryPath
+	fmt.Fprintf(out, "Discovery URL: %s\n", discoveryURL)
+	if showTokenClaims {
+		printTokenClaims(api.token)
+	}
+	client, err := api.client()
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create client: %v", err)
+	}
+
+	// Make discovery request, retrying transient failures with
+	// exponential backoff
+	var body []byte
+	backoff := discoveryBackoff
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		body, retryable, err = fetchDiscovery(ctx, client, discoveryURL, api.token)
+		if err == nil {
+			break
+		}
+		if !retryable || attempt > discoveryRetries {
+			noun := "attempts"
+			if attempt == 1 {
+				noun = "attempt"
+			}
+			return "", nil, fmt.Errorf("%v (after %d %s)", err, attempt, noun)
+		}
+		fmt.Fprintf(warnOut, "⚠️  Discovery attempt %d failed: %v; retrying in %s\n", attempt, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", nil, fmt.Errorf("discovery interrupted: %v", ctx.Err())
+		}
+		backoff *= 2
+	}
+
+	var discovery OAuthDiscovery
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return "", nil, fmt.Errorf("cannot parse discovery response: %v", err)
+	}
+
+	if discovery.TokenEndpoint == "" {
+		return "", nil, fmt.Errorf("no token_endpoint in discovery response")
+	}
+
+	fmt.Fprintf(out, "✅ Discovery successful\n")
+	fmt.Fprintf(out, "   Issuer: %s\n", discovery.Issuer)
+	fmt.Fprintf(out, "   Token Endpoint: %s\n", discovery.TokenEndpoint)
+	checkPKCEMethods(discovery.CodeChallengeMethodsSupported)
+
+	return discovery.TokenEndpoint, body, nil
+}
+
+// fetchDiscovery makes one discovery request, bounded by -timeout, and
+// returns the response body. Network errors and 5xx responses are reported
+// as retryable; other statuses, such as 401 or 403, will not change on a
+// retry, and neither will a cancelled ctx.
+func fetchDiscovery(ctx context.Context, client *http.Client, discoveryURL, token string) ([]byte, bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "GET", discoveryURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("discovery request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= 500, fmt.Errorf("discovery returned HTTP %d", resp.StatusCode)
+	}
+
+	// Parse discovery response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("cannot read discovery response: %v", err)
+	}
+	return body, false, nil
+}
+
+// newKubeAPIRequest builds an authenticated request against the API server
+// and a client that trusts its CA, using the settings from loadKubeAPI.
+// Cancelling ctx aborts the request.
+func newKubeAPIRequest(ctx context.Context, method, path string, body io.Reader) (*http.Client, *http.Request, error) {
+	api, err := loadKubeAPI()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := api.client()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create client: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, api.server+path, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return client, req, nil
+}
+
+// kubeconfigPath, when set, makes API requests with the current context of
+// this kubeconfig instead of the pod's service account (-kubeconfig).
+var kubeconfigPath string
+
+// bearerToken and bearerTokenFile replace the credentials of the API
+// settings with a bearer token, such as a manually minted or impersonated
+// one (-token and -token-file). At most one is set.
+var bearerToken, bearerTokenFile string
+
+// kubeAPI is how to reach and authenticate to the Kubernetes API.
+type kubeAPI struct {
+	config *rest.Config
+	server string
+	source string // where the settings came from, for the report
+	token  string // bearer token, when known before the first request
+}
+
+// client returns an HTTP client for the API server that adds the
+// credentials itself, including those from exec plugins.
+func (a *kubeAPI) client() (*http.Client, error) {
+	return rest.HTTPClientFor(a.config)
+}
+
+// cachedKubeAPI holds the settings once loadKubeAPI has succeeded, so the
+// kubeconfig is only resolved once per run.
+var cachedKubeAPI *kubeAPI
+
+// loadKubeAPI returns the API settings. Inside a pod, detected by the
+// service account token file, they are the service account credentials and
+// kubernetes.default.svc. With -kubeconfig, or outside a pod when a default
+// kubeconfig exists, they come from the kubeconfig's current context.
+func loadKubeAPI() (*kubeAPI, error) {
+	if cachedKubeAPI != nil {
+		return cachedKubeAPI, nil
+	}
+	token, tokenSource, err := overrideToken()
+	if err != nil {
+		return nil, err
+	}
+	var api *kubeAPI
+	if useKubeconfig() {
+		api, err = loadKubeconfigAPI(kubeconfigPath)
+	} else {
+		api, err = loadInClusterAPI(token == "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		// The token alone decides the identity, so drop any other
+		// credentials the kubeconfig also had
+		api.config = rest.AnonymousClientConfig(api.config)
+		api.config.BearerToken = token
+		api.token = token
+		api.source += ", token from " + tokenSource
+	}
+	api.config.Proxy = proxyForRequest
+	api.config.Timeout = timeout
+	cachedKubeAPI = api
+	return api, nil
+}
+
+// overrideToken resolves -token or -token-file, returning the token and
+// the flag it came from, or an empty token when neither is set.
+func overrideToken() (string, string, error) {
+	switch {
+	case bearerToken != "":
+		return bearerToken, "-token", nil
+	case bearerTokenFile != "":
+		data, err := os.ReadFile(bearerTokenFile)
+		if err != nil {
+			return "", "", fmt.Errorf("cannot read -token-file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", "", fmt.Errorf("-token-file %s is empty", bearerTokenFile)
+		}
+		return token, "-token-file " + bearerTokenFile, nil
+	}
+	return "", "", nil
+}
+
+// useKubeconfig reports whether API settings should come from a kubeconfig
+// rather than the service account.
+func useKubeconfig() bool {
+	if kubeconfigPath != "" {
+		return true
+	}
+	if _, err := os.Stat(serviceAccountTokenPath); err == nil {
+		return false
+	}
+	for _, path := range clientcmd.NewDefaultClientConfigLoadingRules().GetLoadingPrecedence() {
+		if _, err := os.Stat(path); path != "" && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadInClusterAPI returns the service account settings. The token is
+// only read when needToken is set, so -token and -token-file work without
+// one mounted.
+func loadInClusterAPI(needToken bool) (*kubeAPI, error) {
+	caPEM, err := readServiceAccountCA()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read service account CA: %v; outside a pod, use -kubeconfig", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("cannot parse service account CA")
+	}
+	api := &kubeAPI{
+		config: &rest.Config{
+			Host:            kubernetesAPIServer,
+			TLSClientConfig: rest.TLSClientConfig{CAData: caPEM},
+		},
+		server: kubernetesAPIServer,
+		source: "in-cluster service account",
+	}
+	if needToken {
+		tokenBytes, err := readServiceAccountToken()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read service account token: %v; outside a pod, use -kubeconfig", err)
+		}
+		api.token = strings.TrimSpace(string(tokenBytes))
+		if api.token == "" {
+			return nil, fmt.Errorf("service account token %s is empty", serviceAccountTokenPath)
+		}
+		api.config.BearerToken = api.token
+	}
+	return api, nil
+}
+
+// loadKubeconfigAPI resolves the current context of a kubeconfig, or of the
+// default kubeconfig ($KUBECONFIG, then ~/.kube/config) when path is empty,
+// with the same loading rules as kubectl. Every credential type client-go
+// supports works, including exec plugins such as "oc login --exec-plugin"
+// and auth providers.
+func loadKubeconfigAPI(path string) (*kubeAPI, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load kubeconfig: %v", err)
+	}
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig context %q: %v", raw.CurrentContext, err)
+	}
+
+	api := &kubeAPI{
+		config: config,
+		server: strings.TrimSuffix(config.Host, "/"),
+		source: fmt.Sprintf("kubeconfig context %q", raw.CurrentContext),
+		token:  config.BearerToken,
+	}
+	if api.token == "" && config.BearerTokenFile != "" {
+		data, err := os.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read kubeconfig token file: %v", err)
+		}
+		api.token = strings.TrimSpace(string(data))
+	}
+	return api, nil
+}
+
+// The API resources this tool reads or writes. OpenShift's config and
+// route APIs have no typed client here, so every object goes through the
+// dynamic client and is converted to a struct holding just the fields used.
+var (
+	configMapsResource      = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secretsResource         = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	servicesResource        = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	deploymentsResource     = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	routesResource          = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+	proxiesResource         = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "proxies"}
+	authenticationsResource = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "authentications"}
+	oauthsResource          = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "oauths"}
+)
+
+// webhookConfigurationsResource returns the resource for validating or
+// mutating webhook configurations.
+func webhookConfigurationsResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: "admissionregistration.k8s.io", Version: "v1", Resource: resource}
+}
+
+// errKubeNotFound is returned when the object does not exist.
+var errKubeNotFound = errors.New("not found")
+
+// kubeDynamicClient returns the client for API objects, built from the
+// settings of loadKubeAPI. Tests replace it with a fake.
+var kubeDynamicClient = func() (dynamic.Interface, error) {
+	api, err := loadKubeAPI()
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(api.config)
+}
+
+// kubeResource returns the client for gvr in namespace, or for the
+// cluster-scoped resource when namespace is empty.
+func kubeResource(gvr schema.GroupVersionResource, namespace string) (dynamic.ResourceInterface, error) {
+	client, err := kubeDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return client.Resource(gvr), nil
+	}
+	return client.Resource(gvr).Namespace(namespace), nil
+}
+
+// kubeError describes a failed API call on an object, wrapping
+// errKubeNotFound when the object does not exist.
+func kubeError(verb string, gvr schema.GroupVersionResource, namespace, name string, err error) error {
+	object := gvr.GroupResource().String() + " " + name
+	if namespace != "" {
+		object = gvr.GroupResource().String() + " " + namespace + "/" + name
+	}
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%s %s: %w", verb, object, errKubeNotFound)
+	}
+	return fmt.Errorf("%s %s failed: %w", verb, object, err)
+}
+
+// kubeGet fetches an object and converts it into v.
+func kubeGet(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string, v interface{}) error {
+	res, err := kubeResource(gvr, namespace)
+	if err != nil {
+		return err
+	}
+	obj, err := res.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return kubeError("get", gvr, namespace, name, err)
+	}
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, v); err != nil {
+		return fmt.Errorf("cannot parse %s %s: %v", gvr.GroupResource(), name, err)
+	}
+	return nil
+}
+
+// kubeList lists the objects in namespace, or in every namespace when it
+// is empty, that match the label selector, and converts the list into v.
+func kubeList(ctx context.Context, gvr schema.GroupVersionResource, namespace, selector string, v interface{}) error {
+	res, err := kubeResource(gvr, namespace)
+	if err != nil {
+		return err
+	}
+	list, err := res.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("list %s failed: %w", gvr.GroupResource(), err)
+	}
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(list.UnstructuredContent(), v); err != nil {
+		return fmt.Errorf("cannot parse %s list: %v", gvr.GroupResource(), err)
+	}
+	return nil
+}
+
+// kubeCreate creates obj, which must carry its apiVersion, kind and
+// metadata.
+func kubeCreate(ctx context.Context, gvr schema.GroupVersionResource, namespace string, obj map[string]interface{}) error {
+	res, err := kubeResource(gvr, namespace)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if _, err := res.Create(ctx, u, metav1.CreateOptions{}); err != nil {
+		return kubeError("create", gvr, namespace, u.GetName(), err)
+	}
+	return nil
+}
+
+// kubeUpdate replaces obj. With a metadata.resourceVersion the update
+// fails with a conflict rather than overwrite a concurrent change.
+func kubeUpdate(ctx context.Context, gvr schema.GroupVersionResource, namespace string, obj map[string]interface{}) error {
+	res, err := kubeResource(gvr, namespace)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if _, err := res.Update(ctx, u, metav1.UpdateOptions{}); err != nil {
+		return kubeError("update", gvr, namespace, u.GetName(), err)
+	}
+	return nil
+}
+
+// configMap is the subset of a core/v1 ConfigMap this tool reads.
+type configMap struct {
+	Data map[string]string `json:"data"`
+}
+
+// getConfigMap fetches a ConfigMap given as "namespace/name".
+func getConfigMap(ctx context.Context, ref string) (*configMap, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("expected namespace/name, got %q", ref)
+	}
+	var cm configMap
+	if err := kubeGet(ctx, configMapsResource, namespace, name, &cm); err != nil {
+		return nil, err
+	}
+	return &cm, nil
+}
+
+// parsePEMCertificates returns every certificate in PEM data, skipping
+// blocks that are not certificates or fail to parse.
+func parsePEMCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+func certFingerprint(cert *x509.Certificate) [sha256.Size]byte {
+	return sha256.Sum256(cert.Raw)
+}
+
+// Well-known locations of the distro CA bundle, in the order Go's own
+// crypto/x509 searches them on Linux.
+var systemBundlePaths = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// checkProxyCAInjection verifies that the bundle produced by the cluster-wide
+// proxy's trusted-CA injection contains both the system roots and every CA
+// from the Proxy's spec.trustedCA ConfigMap. Returns false if any expected CA
+// is missing.
+func checkProxyCAInjection(ctx context.Context, injectedPath, injectedConfigMap, systemPath string) bool {
+	fmt.Fprintln(out, "=== Proxy Trusted-CA Injection Check ===")
+	fmt.Fprintln(out)
+
+	// Injected bundle, from the mounted file or straight from the ConfigMap
+	var injectedPEM []byte
+	if injectedConfigMap != "" {
+		cm, err := getConfigMap(ctx, injectedConfigMap)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot read injected ConfigMap: %v\n", err)
+			return false
+		}
+		injectedPEM = []byte(cm.Data["ca-bundle.crt"])
+		fmt.Fprintf(out, "Injected bundle: ConfigMap %s (key ca-bundle.crt)\n", injectedConfigMap)
+	} else {
+		var err error
+		injectedPEM, err = os.ReadFile(injectedPath)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot read injected bundle %s: %v\n", injectedPath, err)
+			return false
+		}
+		fmt.Fprintf(out, "Injected bundle: %s\n", injectedPath)
+	}
+
+	injected := map[[sha256.Size]byte]bool{}
+	for _, cert := range parsePEMCertificates(injectedPEM) {
+		injected[certFingerprint(cert)] = true
+	}
+	fmt.Fprintf(out, "   %d certificates\n\n", len(injected))
+	if len(injected) == 0 {
+		fmt.Fprintln(errOut, "❌ FAIL: Injected bundle is empty - the injection has not happened")
+		return false
+	}
+
+	ok := true
+
+	// System roots
+	if systemPath == "" {
+		for _, candidate := range systemBundlePaths {
+			// The injected bundle is often mounted over the distro path
+			if candidate == injectedPath && injectedConfigMap == "" {
+				continue
+			}
+			if _, err := os.Stat(candidate); err == nil {
+				systemPath = candidate
+				break
+			}
+		}
+	}
+	if systemPath == "" {
+		fmt.Fprintln(warnOut, "⚠️  WARNING: No system roots bundle found; skipping system roots check")
+	} else if systemPEM, err := os.ReadFile(systemPath); err != nil {
+		fmt.Fprintf(warnOut, "⚠️  WARNING: Cannot read system roots bundle %s: %v\n", systemPath, err)
+	} else {
+		systemCerts := parsePEMCertificates(systemPEM)
+		missing := 0
+		for _, cert := range systemCerts {
+			if !injected[certFingerprint(cert)] {
+				missing++
+			}
+		}
+		fmt.Fprintf(out, "System roots (%s): %d of %d present\n", systemPath, len(systemCerts)-missing, len(systemCerts))
+		if missing > 0 {
+			fmt.Fprintf(errOut, "   ❌ %d system roots missing from the injected bundle\n", missing)
+			ok = false
+		}
+	}
+	fmt.Fprintln(out)
+
+	// User-specified additional CAs from proxy/cluster spec.trustedCA
+	var proxy struct {
+		Spec struct {
+			TrustedCA struct {
+				Name string `json:"name"`
+			} `json:"trustedCA"`
+		} `json:"spec"`
+	}
+	if err := kubeGet(ctx, proxiesResource, "", "cluster", &proxy); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read cluster proxy config: %v\n", err)
+		return false
+	}
+	if proxy.Spec.TrustedCA.Name == "" {
+		fmt.Fprintln(out, "ℹ️  proxy/cluster has no spec.trustedCA; no additional CAs expected")
+	} else {
+		ref := "openshift-config/" + proxy.Spec.TrustedCA.Name
+		cm, err := getConfigMap(ctx, ref)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot read trustedCA ConfigMap %s: %v\n", ref, err)
+			return false
+		}
+		additional := parsePEMCertificates([]byte(cm.Data["ca-bundle.crt"]))
+		fmt.Fprintf(out, "Additional CAs (proxy/cluster trustedCA -> %s): %d\n", ref, len(additional))
+		for _, cert := range additional {
+			if injected[certFingerprint(cert)] {
+				fmt.Fprintf(out, "   ✅ %s\n", cert.Subject.String())
+			} else {
+				fmt.Fprintf(errOut, "   ❌ MISSING: %s\n", cert.Subject.String())
+				ok = false
+			}
+		}
+	}
+	fmt.Fprintln(out)
+
+	if ok {
+		fmt.Fprintln(out, "✅ Injected bundle contains the system roots and all additional CAs")
+	} else {
+		fmt.Fprintln(errOut, "❌ Injected bundle is missing expected CAs - check the injection label and the operator status")
+	}
+	return ok
+}
+
+// webhookConfiguration is the subset of an admissionregistration.k8s.io/v1
+// Validating/MutatingWebhookConfiguration this tool reads.
+type webhookConfiguration struct {
+	Webhooks []struct {
+		Name         string `json:"name"`
+		ClientConfig struct {
+			CABundle []byte  `json:"caBundle"`
+			URL      *string `json:"url"`
+			Service  *struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+				Port      *int   `json:"port"`
+			} `json:"service"`
+		} `json:"clientConfig"`
+	} `json:"webhooks"`
+}
+
+// checkWebhookServingCerts fetches a webhook configuration, probes each
+// webhook's serving endpoint and verifies the served chain against the
+// caBundle the API server will use. Returns false on any mismatch.
+func checkWebhookServingCerts(ctx context.Context, ref string) bool {
+	fmt.Fprintln(out, "=== Admission Webhook Serving Cert Check ===")
+	fmt.Fprintln(out)
+
+	kind, name, ok := strings.Cut(ref, "/")
+	var resource string
+	switch kind {
+	case "validating":
+		resource = "validatingwebhookconfigurations"
+	case "mutating":
+		resource = "mutatingwebhookconfigurations"
+	}
+	if !ok || resource == "" || name == "" {
+		fmt.Fprintf(errOut, "❌ FAIL: -webhook must be validating/<name> or mutating/<name>, got %q\n", ref)
+		return false
+	}
+
+	var config webhookConfiguration
+	if err := kubeGet(ctx, webhookConfigurationsResource(resource), "", name, &config); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read %s: %v\n", ref, err)
+		return false
+	}
+
+	allOK := true
+	for _, webhook := range config.Webhooks {
+		fmt.Fprintf(out, "--- Webhook %s ---\n", webhook.Name)
+
+		// The API server dials the service by its cluster DNS name and
+		// verifies the serving cert for that name
+		var addr, serverName string
+		cc := webhook.ClientConfig
+		switch {
+		case cc.Service != nil:
+			port := 443
+			if cc.Service.Port != nil {
+				port = *cc.Service.Port
+			}
+			serverName = cc.Service.Name + "." + cc.Service.Namespace + ".svc"
+			addr = net.JoinHostPort(serverName, fmt.Sprintf("%d", port))
+		case cc.URL != nil:
+			var err error
+			addr, serverName, err = dialTarget(*cc.URL)
+			if err != nil {
+				fmt.Fprintf(errOut, "❌ FAIL: %v\n\n", err)
+				allOK = false
+				continue
+			}
+		default:
+			fmt.Fprintf(errOut, "❌ FAIL: clientConfig has neither service nor url\n\n")
+			allOK = false
+			continue
+		}
+		fmt.Fprintf(out, "Endpoint: %s\n", addr)
+
+		if len(cc.CABundle) == 0 {
+			// Without a caBundle the API server falls back to its own
+			// system trust, which rarely covers in-cluster serving certs
+			fmt.Fprintf(warnOut, "⚠️  WARNING: No caBundle set; API server will use its system trust roots\n\n")
+			allOK = false
+			continue
+		}
+		roots := x509.NewCertPool()
+		caCerts := parsePEMCertificates(cc.CABundle)
+		for _, cert := range caCerts {
+			roots.AddCert(cert)
+		}
+		fmt.Fprintf(out, "caBundle: %d certificates\n", len(caCerts))
+		if len(caCerts) == 0 {
+			fmt.Fprintf(errOut, "❌ FAIL: caBundle contains no parseable certificates\n\n")
+			allOK = false
+			continue
+		}
+
+		// Capture the served chain without verifying, then verify it
+		// against exactly the caBundle
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+		}}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: Cannot connect to webhook: %v\n\n", err)
+			allOK = false
+			continue
+		}
+		peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+		conn.Close()
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range peers[1:] {
+			intermediates.AddCert(cert)
+		}
+		fmt.Fprintf(out, "Serving cert: %s (issuer %s)\n", peers[0].Subject.String(), peers[0].Issuer.String())
+
+		_, err = peers[0].Verify(x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   validationTime(),
+		})
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ MISMATCH: serving cert does not verify against caBundle: %v\n", err)
+			fmt.Fprintln(errOut, "   → The API server will reject calls to this webhook; re-sync caBundle with the serving cert's CA")
+			allOK = false
+		} else {
+			fmt.Fprintln(out, "✅ Serving cert verifies against caBundle")
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(config.Webhooks) == 0 {
+		fmt.Fprintln(out, "ℹ️  Configuration has no webhooks")
+	}
+	return allOK
+}
+
+// parseDERChain parses certificates captured from a TLS Certificate message.
+// It accepts plain concatenated DER as well as the TLS 1.2 wire framing,
+// where each certificate (and optionally the whole list) is prefixed with a
+// 24-bit length.
+func parseDERChain(data []byte) ([]*x509.Certificate, error) {
+	certs, err := x509.ParseCertificates(data)
+	if err == nil {
+		return certs, nil
+	}
+
+	// Strip an outer certificate_list length if it covers the whole input
+	if len(data) > 3 && int(data[0])<<16|int(data[1])<<8|int(data[2]) == len(data)-3 {
+		data = data[3:]
+	}
+	var framed []*x509.Certificate
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, err
+		}
+		n := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+		if len(data) < 3+n {
+			return nil, err
+		}
+		cert, ferr := x509.ParseCertificate(data[3 : 3+n])
+		if ferr != nil {
+			return nil, ferr
+		}
+		framed = append(framed, cert)
+		data = data[3+n:]
+	}
+	return framed, nil
+}
+
+// decodeCapturedChain runs the probe's trust-store analysis against a chain
+// captured out-of-band instead of a live handshake.
+func decodeCapturedChain(path, serverName string) bool {
+	fmt.Fprintln(out, "=== Captured Chain Analysis ===")
+	fmt.Fprintln(out)
+
+	if path == "" {
+		fmt.Fprintln(errOut, "❌ FAIL: decode-chain mode requires -chain-file")
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read chain file %s: %v\n", path, err)
+		return false
+	}
+	chain, err := parseDERChain(data)
+	if err != nil || len(chain) == 0 {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot parse DER chain: %v\n", err)
+		return false
+	}
+
+	fmt.Fprintf(out, "Decoded %d certificates from %s:\n", len(chain), path)
+	for i, cert := range chain {
+		fmt.Fprintf(out, "  #%d Subject: %s\n", i, cert.Subject.String())
+		fmt.Fprintf(out, "     Issuer:  %s\n", cert.Issuer.String())
+	}
+	fmt.Fprintln(out)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// Build the same three trust stores the live probe uses
+	saPool := x509.NewCertPool()
+	saPEM, saErr := readServiceAccountCA()
+	if saErr == nil && !saPool.AppendCertsFromPEM(saPEM) {
+		saErr = fmt.Errorf("cannot parse service account CA")
+	}
+	systemPool, sysErr := loadSystemCertPool(report)
+	var unionPool *x509.CertPool
+	if sysErr == nil {
+		unionPool = systemPool.Clone()
+		if saErr == nil {
+			unionPool.AppendCertsFromPEM(saPEM)
+		}
+	}
+
+	scenarios := []struct {
+		name string
+		pool *x509.CertPool
+		err  error
+	}{
+		{"Service Account CA Only", saPool, saErr},
+		{"System Trust Store + Service Account CA", unionPool, sysErr},
+		{"System Trust Store Only", systemPool, sysErr},
+	}
+
+	anyOK := false
+	for i, sc := range scenarios {
+		fmt.Fprintf(out, "--- Test %d: %s ---\n", i+1, sc.name)
+		if sc.err != nil {
+			fmt.Fprintf(warnOut, "⚠️  SKIPPED: %v\n\n", sc.err)
+			continue
+		}
+		_, err := chain[0].Verify(x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         sc.pool,
+			Intermediates: intermediates,
+			CurrentTime:   validationTime(),
+		})
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: %v\n\n", err)
+			continue
+		}
+		fmt.Fprintf(out, "✅ SUCCESS: chain verifies\n\n")
+		anyOK = true
+	}
+	return anyOK
+}
+
+// verifyX5C verifies the x5c chain embedded in a JWT header (RFC 7515) against
+// the given roots. The token is read from a file or stdin rather than a
+// flag so it does not end up in shell history or the process list.
+func verifyX5C(jwtPath, bundlePath string) bool {
+	fmt.Fprintln(out, "=== JWT x5c Chain Verification ===")
+	fmt.Fprintln(out)
+
+	if bundlePath == "" {
+		fmt.Fprintln(errOut, "❌ FAIL: -trust-bundle is required")
+		return false
+	}
+	var raw []byte
+	var err error
+	if jwtPath == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(jwtPath)
+	}
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read JWT: %v\n", err)
+		return false
+	}
+
+	// A full JWT or just its header; either way the header is the first
+	// segment
+	segment, _, _ := strings.Cut(strings.TrimSpace(string(raw)), ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: JWT header is not base64url: %v\n", err)
+		return false
+	}
+	var header struct {
+		Alg string   `json:"alg"`
+		Kid string   `json:"kid"`
+		X5C []string `json:"x5c"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot parse JWT header: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "alg: %s\n", header.Alg)
+	if header.Kid != "" {
+		fmt.Fprintf(out, "kid: %s\n", header.Kid)
+	}
+	if len(header.X5C) == 0 {
+		fmt.Fprintln(errOut, "❌ FAIL: JWT header has no x5c chain")
+		return false
+	}
+
+	// x5c entries are standard (not URL-safe) base64 DER
+	var chain []*x509.Certificate
+	for i, entry := range header.X5C {
+		der, err := base64.StdEncoding.DecodeString(entry)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: x5c[%d] is not base64: %v\n", i, err)
+			return false
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: x5c[%d] is not a certificate: %v\n", i, err)
+			return false
+		}
+		chain = append(chain, cert)
+	}
+	fmt.Fprintf(out, "x5c chain (%d certificates):\n", len(chain))
+	for i, cert := range chain {
+		fmt.Fprintf(out, "   %d. %s\n", i+1, cert.Subject.String())
+	}
+	fmt.Fprintf(out, "Signing certificate: %s\n\n", chain[0].Subject.String())
+
+	bundlePEM, err := os.ReadFile(bundlePath)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read trust bundle: %v\n", err)
+		return false
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundlePEM) {
+		fmt.Fprintf(errOut, "❌ FAIL: %s contains no certificates\n", bundlePath)
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// Token signing certs are not TLS server certs, so any EKU is accepted
+	verified, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   validationTime(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: x5c chain does not verify against %s: %v\n", bundlePath, err)
+		return false
+	}
+	fmt.Fprintf(out, "✅ x5c chain verifies against %s\n", bundlePath)
+	fmt.Fprintf(out, "   → Anchored at %s\n", verified[0][len(verified[0])-1].Subject.String())
+	return true
+}
+
+// sourceList implements flag.Value for the repeatable -source flag.
+type sourceList []string
+
+func (s *sourceList) String() string { return strings.Join(*s, ",") }
+
+func (s *sourceList) Set(value string) error {
+	kind, _, _ := strings.Cut(value, ":")
+	switch kind {
+	case "file", "configmap", "system", "system-root":
+	default:
+		return fmt.Errorf("unknown source kind %q (expected file, configmap, system or system-root)", kind)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// systemBundleCerts returns the certificates of the first distro CA bundle
+// found, or of -system-ca-fallback. x509.SystemCertPool cannot be
+// enumerated, so the PEM is read directly.
+func systemBundleCerts() ([]*x509.Certificate, error) {
+	paths := systemBundlePaths
+	if systemCAFallback != "" {
+		paths = append([]string{systemCAFallback}, paths...)
+	}
+	for _, path := range paths {
+		if data, err := os.ReadFile(path); err == nil {
+			return parsePEMCertificates(data), nil
+		}
+	}
+	return nil, errSystemTrustUnavailable
+}
+
+// readTrustSource loads the certificates of one -source entry.
+func readTrustSource(ctx context.Context, source string) ([]*x509.Certificate, error) {
+	kind, arg, _ := strings.Cut(source, ":")
+	switch kind {
+	case "file":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", arg, err)
+		}
+		return parsePEMCertificates(data), nil
+	case "configmap":
+		ref, key, ok := strings.Cut(arg, "#")
+		if !ok {
+			key = "ca-bundle.crt"
+		}
+		cm, err := getConfigMap(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		return parsePEMCertificates([]byte(cm.Data[key])), nil
+	case "system":
+		return systemBundleCerts()
+	case "system-root":
+		all, err := systemBundleCerts()
+		if err != nil {
+			return nil, err
+		}
+		var matched []*x509.Certificate
+		for _, cert := range all {
+			if cert.Subject.CommonName == arg {
+				matched = append(matched, cert)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no system root with CN %q", arg)
+		}
+		return matched, nil
+	}
+	return nil, fmt.Errorf("unknown source %q", source)
+}
+
+// assembleBundle merges the sources into a normalized bundle: deduplicated
+// by fingerprint and sorted by subject, so identical inputs always produce
+// byte-identical output.
+func assembleBundle(ctx context.Context, sources []string) ([]byte, int, error) {
+	seen := map[[sha256.Size]byte]bool{}
+	var certs []*x509.Certificate
+	for _, source := range sources {
+		sourceCerts, err := readTrustSource(ctx, source)
+		if err != nil {
+			return nil, 0, fmt.Errorf("source %s: %v", source, err)
+		}
+		added := 0
+		for _, cert := range sourceCerts {
+			if fp := certFingerprint(cert); !seen[fp] {
+				seen[fp] = true
+				certs = append(certs, cert)
+				added++
+			}
+		}
+		fmt.Fprintf(out, "   Source %s: %d certificates (%d new)\n", source, len(sourceCerts), added)
+	}
+	sort.Slice(certs, func(i, j int) bool {
+		si, sj := certs[i].Subject.String(), certs[j].Subject.String()
+		if si != sj {
+			return si < sj
+		}
+		fi, fj := certFingerprint(certs[i]), certFingerprint(certs[j])
+		return bytes.Compare(fi[:], fj[:]) < 0
+	})
+
+	var bundle bytes.Buffer
+	for _, cert := range certs {
+		fmt.Fprintf(&bundle, "# %s\n", cert.Subject.String())
+		pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return bundle.Bytes(), len(certs), nil
+}
+
+// reconcileOnce assembles the desired bundle and writes it to the target
+// ConfigMap's ca-bundle.crt key only if its content changed. Other keys,
+// labels and annotations on an existing ConfigMap are preserved.
+func reconcileOnce(ctx context.Context, sources []string, target string) error {
+	namespace, name, ok := strings.Cut(target, "/")
+	if !ok || namespace == "" || name == "" {
+		return fmt.Errorf("expected namespace/name, got %q", target)
+	}
+	desired, count, err := assembleBundle(ctx, sources)
+	if err != nil {
+		return err
+	}
+	desiredHash := sha256.Sum256(desired)
+	fmt.Fprintf(out, "   Desired bundle: %d certificates, sha256 %x\n", count, desiredHash[:8])
+
+	const key = "ca-bundle.crt"
+	var existing map[string]interface{}
+	if err := kubeGet(ctx, configMapsResource, namespace, name, &existing); err != nil {
+		if !errors.Is(err, errKubeNotFound) {
+			return err
+		}
+		cm := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+			"data":       map[string]interface{}{key: string(desired)},
+		}
+		if err := kubeCreate(ctx, configMapsResource, namespace, cm); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "   ✅ Created ConfigMap %s\n", target)
+		return nil
+	}
+
+	data, _ := existing["data"].(map[string]interface{})
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	current, _ := data[key].(string)
+	if sha256.Sum256([]byte(current)) == desiredHash {
+		fmt.Fprintf(out, "   ✅ ConfigMap %s is up to date; no write\n", target)
+		return nil
+	}
+	// The resourceVersion from the get makes the update fail rather than
+	// overwrite a concurrent change
+	data[key] = string(desired)
+	existing["data"] = data
+	if err := kubeUpdate(ctx, configMapsResource, namespace, existing); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "   ✅ Updated ConfigMap %s (%d certificates)\n", target, count)
+	return nil
+}
+
+// reconcileTrustBundle keeps the target ConfigMap in the desired state,
+// once or every interval. In a loop, failures are reported and retried on
+// the next pass rather than ending the run; cancelling ctx ends it.
+func reconcileTrustBundle(ctx context.Context, sources []string, target string, interval time.Duration) bool {
+	fmt.Fprintln(out, "=== Trust Bundle Reconcile ===")
+	fmt.Fprintln(out)
+	if len(sources) == 0 || target == "" {
+		fmt.Fprintln(errOut, "❌ FAIL: reconcile mode needs at least one -source and a -reconcile-target")
+		return false
+	}
+
+	for {
+		fmt.Fprintf(out, "Reconcile at %s\n", time.Now().UTC().Format(time.RFC3339))
+		err := reconcileOnce(ctx, sources, target)
+		if err != nil {
+			fmt.Fprintf(errOut, "   ❌ FAIL: %v\n", err)
+		}
+		fmt.Fprintln(out)
+		if interval == 0 {
+			return err == nil
+		}
+		select {
+		case <-ctx.Done():
+			return err == nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// trustLocation is where a component reads its CA bundle from.
+type trustLocation struct {
+	kind      string // "configmap" or "secret"
+	namespace string
+	name      string
+	key       string
+}
+
+func (l trustLocation) String() string {
+	return fmt.Sprintf("%s %s/%s (key %s)", l.kind, l.namespace, l.name, l.key)
+}
+
+// clusterDefaultTrust is the merged bundle the network operator maintains
+// from the system roots plus the proxy's additional trusted CAs.
+var clusterDefaultTrust = trustLocation{"configmap", "openshift-config-managed", "trusted-ca-bundle", "ca-bundle.crt"}
+
+// componentTrustRegistry records where the components we operate keep
+// their own copy of the trust bundle.
+var componentTrustRegistry = map[string]trustLocation{
+	"image-registry": {"configmap", "openshift-image-registry", "trusted-ca", "ca-bundle.crt"},
+	"oauth-server":   {"configmap", "openshift-authentication", "v4-0-config-system-trusted-ca-bundle", "ca-bundle.crt"},
+	"console":        {"configmap", "openshift-console", "trusted-ca-bundle", "ca-bundle.crt"},
+	"ingress":        {"configmap", "openshift-config-managed", "default-ingress-cert", "ca-bundle.crt"},
+	"service-ca":     {"configmap", "openshift-config-managed", "service-ca", "ca-bundle.crt"},
+	"service-ca-key": {"secret", "openshift-service-ca", "signing-key", "tls.crt"},
+	"odh-trusted-ca": {"configmap", "opendatahub", "odh-trusted-ca-bundle", "ca-bundle.crt"},
+	"odh-custom-ca":  {"configmap", "opendatahub", "odh-trusted-ca-bundle", "odh-ca-bundle.crt"},
+	// kube-auth-proxy trusts the service account CA by default, which is
+	// the projected kube-root-ca.crt
+	"kube-auth-proxy": {"configmap", "openshift-ingress", "kube-root-ca.crt", "ca.crt"},
+}
+
+// readTrustLocation fetches the PEM bundle stored at a trust location.
+func readTrustLocation(ctx context.Context, loc trustLocation) ([]*x509.Certificate, error) {
+	var bundle []byte
+	switch loc.kind {
+	case "configmap":
+		cm, err := getConfigMap(ctx, loc.namespace+"/"+loc.name)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := cm.Data[loc.key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", loc.key)
+		}
+		bundle = []byte(value)
+	case "secret":
+		// Secret data is base64 in the object, which the converter decodes into []byte
+		var secret struct {
+			Data map[string][]byte `json:"data"`
+		}
+		if err := kubeGet(ctx, secretsResource, loc.namespace, loc.name, &secret); err != nil {
+			return nil, err
+		}
+		value, ok := secret.Data[loc.key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", loc.key)
+		}
+		bundle = value
+	default:
+		return nil, fmt.Errorf("unknown location kind %q", loc.kind)
+	}
+	return parsePEMCertificates(bundle), nil
+}
+
+// checkComponentTrust validates the CA bundle a component reads and
+// compares it against the cluster default trust bundle.
+func checkComponentTrust(ctx context.Context, component string) bool {
+	var names []string
+	for name := range componentTrustRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if component == "" || component == "list" {
+		fmt.Fprintln(out, "Known components:")
+		for _, name := range names {
+			fmt.Fprintf(out, "  %-18s %s\n", name, componentTrustRegistry[name])
+		}
+		return component == "list"
+	}
+
+	loc, ok := componentTrustRegistry[component]
+	if !ok {
+		fmt.Fprintf(errOut, "❌ Unknown component %q (known: %s)\n", component, strings.Join(names, ", "))
+		return false
+	}
+
+	fmt.Fprintf(out, "=== Component Trust Check: %s ===\n\n", component)
+	fmt.Fprintf(out, "Component bundle: %s\n", loc)
+	certs, err := readTrustLocation(ctx, loc)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot read component bundle: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "   %d certificates\n", len(certs))
+	if len(certs) == 0 {
+		fmt.Fprintln(errOut, "❌ FAIL: Component bundle contains no certificates")
+		return false
+	}
+
+	now := time.Now()
+	ok = true
+	for _, cert := range certs {
+		if now.After(cert.NotAfter) {
+			fmt.Fprintf(errOut, "   ❌ Expired: %s (NotAfter %s)\n", cert.Subject.String(), cert.NotAfter.Format("2006-01-02"))
+			ok = false
+		}
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintf(out, "Cluster default: %s\n", clusterDefaultTrust)
+	defaults, err := readTrustLocation(ctx, clusterDefaultTrust)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  WARNING: Cannot read cluster default bundle, skipping drift check: %v\n", err)
+		return ok
+	}
+	fmt.Fprintf(out, "   %d certificates\n\n", len(defaults))
+
+	inComponent := map[[sha256.Size]byte]bool{}
+	for _, cert := range certs {
+		inComponent[certFingerprint(cert)] = true
+	}
+	inDefault := map[[sha256.Size]byte]bool{}
+	for _, cert := range defaults {
+		inDefault[certFingerprint(cert)] = true
+	}
+
+	var extra, missing []*x509.Certificate
+	for _, cert := range certs {
+		if !inDefault[certFingerprint(cert)] {
+			extra = append(extra, cert)
+		}
+	}
+	for _, cert := range defaults {
+		if !inComponent[certFingerprint(cert)] {
+			missing = append(missing, cert)
+		}
+	}
+
+	// Component-specific CAs (e.g. the service CA) are expected extras, so
+	// only report them; missing cluster roots is the drift that hurts
+	fmt.Fprintf(out, "Only in component bundle: %d\n", len(extra))
+	for _, cert := range extra {
+		fmt.Fprintf(out, "   ℹ️  %s\n", cert.Subject.String())
+	}
+	fmt.Fprintf(out, "Missing from component bundle: %d\n", len(missing))
+	const maxListed = 10
+	for i, cert := range missing {
+		if i == maxListed {
+			fmt.Fprintf(out, "   ... and %d more\n", len(missing)-maxListed)
+			break
+		}
+		fmt.Fprintf(warnOut, "   ⚠️  %s\n", cert.Subject.String())
+	}
+	fmt.Fprintln(out)
+
+	switch {
+	case !ok:
+		fmt.Fprintln(errOut, "❌ Component bundle contains expired certificates")
+	case len(missing) > 0:
+		fmt.Fprintln(warnOut, "⚠️  Component bundle has drifted from the cluster default")
+	default:
+		fmt.Fprintln(out, "✅ Component bundle includes the full cluster default trust")
+	}
+	return ok
+}
+
+// clientHelloProfile is the part of a browser's ClientHello that crypto/tls
+// lets a client influence.
+type clientHelloProfile struct {
+	cipherSuites []uint16
+	curves       []tls.CurveID
+	nextProtos   []string
+}
+
+// clientHelloProfiles approximate current browser ClientHellos. crypto/tls
+// ignores cipher suite order, picks its own extension order and never sends
+// GREASE, so the resulting JA3 will not equal the browser's; it does show
+// what a WAF sees when our handshake offers the browser's parameters.
+var clientHelloProfiles = map[string]clientHelloProfile{
+	"chrome": {
+		cipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA, tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+		curves:     []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		nextProtos: []string{"h2", "http/1.1"},
+	},
+	"firefox": {
+		cipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA, tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA, tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+		curves:     []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		nextProtos: []string{"h2", "http/1.1"},
+	},
+	"safari": {
+		cipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA, tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA, tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		},
+		curves:     []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		nextProtos: []string{"h2", "http/1.1"},
+	},
+}
+
+// applyClientHelloProfile shapes a TLS config according to -ja3-profile.
+func applyClientHelloProfile(cfg *tls.Config) {
+	profile, ok := clientHelloProfiles[ja3Profile]
+	if !ok {
+		return
+	}
+	cfg.CipherSuites = profile.cipherSuites
+	cfg.CurvePreferences = profile.curves
+	cfg.NextProtos = profile.nextProtos
+}
+
+// helloRecorder captures the first write on a connection, which for a TLS
+// client is the ClientHello record.
+type helloRecorder struct {
+	net.Conn
+	hello []byte
+}
+
+func (r *helloRecorder) Write(b []byte) (int, error) {
+	if r.hello == nil {
+		r.hello = append([]byte{}, b...)
+	}
+	return r.Conn.Write(b)
+}
+
+// reportJA3 performs one handshake with the profiled config, solely to
+// capture the ClientHello the probes will send, and prints its JA3.
+func reportJA3(ctx context.Context, rawURL string) {
+	fmt.Fprintf(out, "--- ClientHello Profile: %s ---\n", ja3Profile)
+
+	addr, serverName, err := dialTarget(rawURL)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot compute JA3: %v\n\n", err)
+		return
+	}
+	raw, stop, err := dialRaw(ctx, addr)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot compute JA3: %v\n\n", err)
+		return
+	}
+	defer stop()
+
+	// Verification does not change the ClientHello, and the outcome of
+	// this handshake is irrelevant; only the bytes we sent matter
+	cfg := &tls.Config{ServerName: serverName, InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}
+	applyClientHelloProfile(cfg)
+	rec := &helloRecorder{Conn: raw}
+	tls.Client(rec, cfg).Handshake()
+
+	ja3, err := ja3String(rec.hello)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot compute JA3: %v\n\n", err)
+		return
+	}
+	sum := md5.Sum([]byte(ja3))
+	fmt.Fprintf(out, "JA3:      %s\n", ja3)
+	fmt.Fprintf(out, "JA3 hash: %s\n", hex.EncodeToString(sum[:]))
+	fmt.Fprintln(out, "(crypto/tls controls cipher and extension order, so this approximates rather than reproduces the browser's JA3)")
+	fmt.Fprintln(out)
+}
+
+// captureHandshakeChain does a bare TLS handshake and returns the chain the
+// server presented, without verifying it.
+func captureHandshakeChain(ctx context.Context, rawURL string) ([]*x509.Certificate, error) {
+	addr, serverName, err := dialTarget(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+}
+
+// checkServedChainOrder checks the server's Certificate message: the leaf
+// first, each following cert the issuer of the one before it, no
+// duplicates, and no root. Clients are lenient about some of this, but
+// strict ones and OCSP stapling are not.
+func checkServedChainOrder(ctx context.Context, rawURL string) bool {
+	fmt.Fprintln(out, "--- Served Chain Order ---")
+
+	chain, err := captureHandshakeChain(ctx, rawURL)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot capture served chain: %v\n", err)
+		return false
+	}
+	for i, cert := range chain {
+		fmt.Fprintf(out, "   %d. %s\n", i+1, cert.Subject.String())
+	}
+
+	ok := true
+	if len(chain) == 0 {
+		fmt.Fprintln(errOut, "❌ Server sent no certificates")
+		return false
+	}
+	if chain[0].IsCA {
+		fmt.Fprintf(errOut, "❌ First certificate is a CA, not the leaf: %s\n", chain[0].Subject.String())
+		ok = false
+	}
+
+	seen := map[[sha256.Size]byte]int{}
+	for i, cert := range chain {
+		fp := certFingerprint(cert)
+		if first, dup := seen[fp]; dup {
+			fmt.Fprintf(errOut, "❌ Certificate %d is a duplicate of certificate %d\n", i+1, first+1)
+			ok = false
+			continue
+		}
+		seen[fp] = i
+
+		if bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil {
+			fmt.Fprintf(errOut, "❌ Certificate %d is a self-signed root; servers should not send their root: %s\n", i+1, cert.Subject.String())
+			ok = false
+		}
+		if i == 0 {
+			continue
+		}
+		prev := chain[i-1]
+		if !bytes.Equal(prev.RawIssuer, cert.RawSubject) || prev.CheckSignatureFrom(cert) != nil {
+			fmt.Fprintf(errOut, "❌ Certificate %d did not issue certificate %d (%s is issued by %s)\n", i+1, i, prev.Subject.String(), prev.Issuer.String())
+			ok = false
+		}
+	}
+
+	if ok {
+		fmt.Fprintln(out, "✅ Chain is leaf-first, correctly ordered and excludes the root")
+	}
+	return ok
+}
+
+// checkTokenExchangeChain POSTs a throwaway authorization_code grant to the
+// token endpoint, as kube-auth-proxy does on login, and validates the chain
+// served on that connection. Pooling or SNI-based routing can put a real
+// request on a different backend than a bare handshake, so the chain is
+// also compared with one from a plain probe.
+func checkTokenExchangeChain(ctx context.Context, tokenURL string) bool {
+	fmt.Fprintln(out, "--- Token Exchange Chain ---")
+
+	// Trust what kube-auth-proxy trusts with --use-system-trust-store=true
+	roots, err := loadSystemCertPool(report)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  %v; using the service account CA only\n", err)
+		roots = x509.NewCertPool()
+	}
+	if caPEM, err := readServiceAccountCA(); err == nil {
+		roots.AppendCertsFromPEM(caPEM)
+	}
+
+	// Verification is done explicitly below so the chain is captured even
+	// when it would not validate
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: newProbeTransport(&tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+		}),
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"tls-probe-invalid-code"},
+		"client_id":    {"tls-probe"},
+		"redirect_uri": {"https://localhost/oauth/callback"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: %v\n", err)
+		return false
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Token request failed: %v\n", err)
+		return false
+	}
+	resp.Body.Close()
+	// The grant is bogus, so any HTTP status is expected; only TLS matters
+	fmt.Fprintf(out, "Token request: HTTP %d (rejection expected for a dummy grant)\n", resp.StatusCode)
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		fmt.Fprintln(errOut, "❌ FAIL: No peer certificates captured from the token exchange")
+		return false
+	}
+	chain := resp.TLS.PeerCertificates
+	fmt.Fprintf(out, "Token exchange chain (%d certificates):\n", len(chain))
+	for i, cert := range chain {
+		fmt.Fprintf(out, "   %d. %s\n", i+1, cert.Subject.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	u, _ := url.Parse(tokenURL)
+	ok := true
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       u.Hostname(),
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   validationTime(),
+	}); err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Token exchange chain does not validate: %v\n", err)
+		ok = false
+	} else {
+		fmt.Fprintln(out, "✅ Token exchange chain validates (system CAs + service account CA)")
+	}
+	fmt.Fprintln(out)
+
+	plain, err := captureHandshakeChain(ctx, tokenURL)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot capture plain-probe chain for comparison: %v\n", err)
+		return ok
+	}
+	same := len(plain) == len(chain)
+	for i := 0; same && i < len(chain); i++ {
+		same = certFingerprint(plain[i]) == certFingerprint(chain[i])
+	}
+	if same {
+		fmt.Fprintln(out, "✅ Token exchange and plain probe were served the same chain")
+		return ok
+	}
+	fmt.Fprintln(warnOut, "⚠️  Token exchange chain DIFFERS from the plain-probe chain:")
+	for i, cert := range plain {
+		fmt.Fprintf(warnOut, "   %d. %s\n", i+1, cert.Subject.String())
+	}
+	fmt.Fprintln(warnOut, "   → Requests are being routed to a backend with a different certificate")
+	return false
+}
+
+// eofRecorder notes whether the underlying connection hit EOF, so a TLS
+// close_notify (which crypto/tls also reports as io.EOF) can be told apart
+// from the peer closing TCP without one.
+type eofRecorder struct {
+	net.Conn
+	sawEOF bool
+}
+
+func (r *eofRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if err == io.EOF {
+		r.sawEOF = true
+	}
+	return n, err
+}
+
+// probeCloseNotify makes one request with "Connection: close" and reports
+// how the server ended the connection: with a close_notify alert (clean),
+// a bare TCP FIN, or a reset. Load balancers that truncate connections
+// show up as the latter two and cause intermittent client errors.
+func probeCloseNotify(ctx context.Context, rawURL string) {
+	fmt.Fprintln(out, "--- close_notify Check ---")
+
+	addr, serverName, err := dialTarget(rawURL)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot check close_notify: %v\n", err)
+		return
+	}
+	u, _ := url.Parse(rawURL)
+
+	raw, stop, err := dialRaw(ctx, addr)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot check close_notify: %v\n", err)
+		return
+	}
+	defer stop()
+
+	// Trust is covered by the probes above; only connection teardown
+	// matters here
+	rec := &eofRecorder{Conn: raw}
+	conn := tls.Client(rec, &tls.Config{ServerName: serverName, InsecureSkipVerify: true, MinVersion: tls.VersionTLS12})
+	if err := conn.Handshake(); err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Cannot check close_notify: handshake failed: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", u.RequestURI(), u.Host)
+	n, err := io.Copy(io.Discard, conn)
+
+	fmt.Fprintf(out, "Read %d bytes before the connection ended\n", n)
+	var netErr net.Error
+	switch {
+	case err == nil && !rec.sawEOF:
+		fmt.Fprintln(out, "✅ Clean close: server sent close_notify")
+	case err == nil:
+		fmt.Fprintln(warnOut, "⚠️  Abrupt close: server closed TCP without close_notify")
+		fmt.Fprintln(warnOut, "   → Something between client and server may be truncating connections")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		fmt.Fprintf(warnOut, "⚠️  Server did not close the connection within %s\n", timeout)
+	default:
+		fmt.Fprintf(warnOut, "⚠️  Abrupt close: %v\n", err)
+		fmt.Fprintln(warnOut, "   → Something between client and server may be truncating connections")
+	}
+}
+
+// isGREASE reports whether v is a GREASE value, which JA3 ignores.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// ja3String computes the JA3 fingerprint string of a ClientHello record:
+// SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats.
+func ja3String(record []byte) (string, error) {
+	errShort := errors.New("truncated ClientHello")
+	if len(record) < 5+4 || record[0] != 22 || record[5] != 1 {
+		return "", errors.New("not a ClientHello record")
+	}
+	msg := record[9:]
+	if len(msg) < 2+32+1 {
+		return "", errShort
+	}
+	version := binary.BigEndian.Uint16(msg)
+	msg = msg[2+32:]
+
+	// session_id
+	n := int(msg[0])
+	if len(msg) < 1+n+2 {
+		return "", errShort
+	}
+	msg = msg[1+n:]
+
+	uint16List := func(b []byte) []string {
+		var out []string
+		for i := 0; i+1 < len(b); i += 2 {
+			if v := binary.BigEndian.Uint16(b[i:]); !isGREASE(v) {
+				out = append(out, fmt.Sprintf("%d", v))
+			}
+		}
+		return out
+	}
+
+	// cipher_suites
+	n = int(binary.BigEndian.Uint16(msg))
+	if len(msg) < 2+n+1 {
+		return "", errShort
+	}
+	ciphers := uint16List(msg[2 : 2+n])
+	msg = msg[2+n:]
+
+	// compression_methods
+	n = int(msg[0])
+	if len(msg) < 1+n {
+		return "", errShort
+	}
+	msg = msg[1+n:]
+
+	var exts, curves, formats []string
+	if len(msg) >= 2 {
+		msg = msg[2:]
+		for len(msg) >= 4 {
+			extType := binary.BigEndian.Uint16(msg)
+			extLen := int(binary.BigEndian.Uint16(msg[2:]))
+			if len(msg) < 4+extLen {
+				return "", errShort
+			}
+			data := msg[4 : 4+extLen]
+			msg = msg[4+extLen:]
+			if isGREASE(extType) {
+				continue
+			}
+			exts = append(exts, fmt.Sprintf("%d", extType))
+			switch extType {
+			case 10: // supported_groups
+				if len(data) >= 2 {
+					curves = uint16List(data[2:])
+				}
+			case 11: // ec_point_formats
+				if len(data) >= 1 {
+					for _, f := range data[1:] {
+						formats = append(formats, fmt.Sprintf("%d", f))
+					}
+				}
+			}
+		}
+	}
+
+	return fmt.Sprintf("%d,%s,%s,%s,%s", version,
+		strings.Join(ciphers, "-"), strings.Join(exts, "-"),
+		strings.Join(curves, "-"), strings.Join(formats, "-")), nil
+}
+
+// authTrustTarget is an identity provider issuer together with the CA the
+// platform is configured to trust it with.
+type authTrustTarget struct {
+	name      string
+	source    string // the CR field the target came from
+	issuerURL string
+	caRef     string // namespace/name of the CA ConfigMap, empty for system trust
+	caKey     string
+}
+
+// authTrustTargets collects issuers and their CA references from the
+// Authentication (OIDC providers) and OAuth (OpenID identity providers)
+// cluster configs.
+func authTrustTargets(ctx context.Context) ([]authTrustTarget, error) {
+	var authn struct {
+		Spec struct {
+			Type          string `json:"type"`
+			OIDCProviders []struct {
+				Name   string `json:"name"`
+				Issuer struct {
+					IssuerURL                  string `json:"issuerURL"`
+					IssuerCertificateAuthority struct {
+						Name string `json:"name"`
+					} `json:"issuerCertificateAuthority"`
+				} `json:"issuer"`
+			} `json:"oidcProviders"`
+		} `json:"spec"`
+	}
+	if err := kubeGet(ctx, authenticationsResource, "", "cluster", &authn); err != nil {
+		return nil, fmt.Errorf("cannot read authentication/cluster: %v", err)
+	}
+	authType := authn.Spec.Type
+	if authType == "" {
+		authType = "IntegratedOAuth"
+	}
+	fmt.Fprintf(out, "authentication/cluster spec.type: %s\n", authType)
+
+	var targets []authTrustTarget
+	for i, p := range authn.Spec.OIDCProviders {
+		t := authTrustTarget{
+			name:      p.Name,
+			source:    fmt.Sprintf("authentication/cluster spec.oidcProviders[%d].issuer.issuerCertificateAuthority", i),
+			issuerURL: p.Issuer.IssuerURL,
+			caKey:     "ca-bundle.crt",
+		}
+		if p.Issuer.IssuerCertificateAuthority.Name != "" {
+			t.caRef = "openshift-config/" + p.Issuer.IssuerCertificateAuthority.Name
+		}
+		targets = append(targets, t)
+	}
+
+	var oauth struct {
+		Spec struct {
+			IdentityProviders []struct {
+				Name   string `json:"name"`
+				Type   string `json:"type"`
+				OpenID *struct {
+					Issuer string `json:"issuer"`
+					CA     struct {
+						Name string `json:"name"`
+					} `json:"ca"`
+				} `json:"openID"`
+			} `json:"identityProviders"`
+		} `json:"spec"`
+	}
+	if authType == "IntegratedOAuth" {
+		if err := kubeGet(ctx, oauthsResource, "", "cluster", &oauth); err != nil {
+			return nil, fmt.Errorf("cannot read oauth/cluster: %v", err)
+		}
+	}
+	for i, idp := range oauth.Spec.IdentityProviders {
+		if idp.OpenID == nil {
+			continue
+		}
+		t := authTrustTarget{
+			name:      idp.Name,
+			source:    fmt.Sprintf("oauth/cluster spec.identityProviders[%d].openID.ca", i),
+			issuerURL: idp.OpenID.Issuer,
+			caKey:     "ca.crt",
+		}
+		if idp.OpenID.CA.Name != "" {
+			t.caRef = "openshift-config/" + idp.OpenID.CA.Name
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// checkAuthConfigTrust validates each configured issuer's live TLS endpoint
+// against exactly the CA the platform itself is configured to use for it.
+func checkAuthConfigTrust(ctx context.Context) bool {
+	fmt.Fprintln(out, "=== Auth Config Trust Check ===")
+	fmt.Fprintln(out)
+
+	targets, err := authTrustTargets(ctx)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: %v\n", err)
+		return false
+	}
+	fmt.Fprintln(out)
+	if len(targets) == 0 {
+		fmt.Fprintln(out, "ℹ️  No OIDC providers or OpenID identity providers configured")
+		return true
+	}
+
+	allOK := true
+	for _, t := range targets {
+		fmt.Fprintf(out, "--- %s ---\n", t.name)
+		fmt.Fprintf(out, "Issuer: %s\n", t.issuerURL)
+
+		var pool *x509.CertPool
+		if t.caRef == "" {
+			fmt.Fprintf(out, "CA:     none referenced in %s; platform uses system trust\n", t.source)
+			pool, err = loadSystemCertPool(report)
+			if err != nil {
+				fmt.Fprintf(warnOut, "⚠️  SKIPPED: %v\n\n", err)
+				continue
+			}
+		} else {
+			fmt.Fprintf(out, "CA:     %s key %s (from %s)\n", t.caRef, t.caKey, t.source)
+			cm, err := getConfigMap(ctx, t.caRef)
+			if err != nil {
+				fmt.Fprintf(errOut, "❌ FAIL: Cannot read CA ConfigMap: %v\n\n", err)
+				allOK = false
+				continue
+			}
+			pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(cm.Data[t.caKey])) {
+				fmt.Fprintf(errOut, "❌ FAIL: %s key %s contains no certificates\n\n", t.caRef, t.caKey)
+				allOK = false
+				continue
+			}
+		}
+
+		client := &http.Client{
+			Timeout: timeout,
+			Transport: newProbeTransport(&tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			}),
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(t.issuerURL, "/")+"/.well-known/openid-configuration", nil)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: %v\n\n", err)
+			allOK = false
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(errOut, "❌ FAIL: %v\n", err)
+			fmt.Fprintln(errOut, "   → The platform will not be able to reach this issuer with its configured CA")
+			allOK = false
+		} else {
+			resp.Body.Close()
+			fmt.Fprintf(out, "✅ SUCCESS: HTTP %d\n", resp.StatusCode)
+			fmt.Fprintln(out, "   → Issuer's certificate is trusted by the configured CA")
+		}
+		fmt.Fprintln(out)
+	}
+	return allOK
+}
+
+// fleetEndpoint is an HTTPS endpoint derived from a Route or Service.
+type fleetEndpoint struct {
+	namespace, name, url string
+}
+
+// fleetEndpoints lists Routes or Services matching selector, in the given
+// namespaces or cluster-wide, and derives an HTTPS URL for each. Routes
+// without TLS and Services without an https/443 port are skipped.
+func fleetEndpoints(ctx context.Context, resource, selector string, namespaces []string) ([]fleetEndpoint, error) {
+	var gvr schema.GroupVersionResource
+	switch resource {
+	case "routes":
+		gvr = routesResource
+	case "services":
+		gvr = servicesResource
+	default:
+		return nil, fmt.Errorf("unknown -fleet-resource %q (expected routes or services)", resource)
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var endpoints []fleetEndpoint
+	for _, namespace := range namespaces {
+		var list struct {
+			Items []struct {
+				Metadata struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+				Spec struct {
+					Host  string           `json:"host"`
+					TLS   *json.RawMessage `json:"tls"`
+					Ports []struct {
+						Name string `json:"name"`
+						Port int    `json:"port"`
+					} `json:"ports"`
+				} `json:"spec"`
+			} `json:"items"`
+		}
+		if err := kubeList(ctx, gvr, namespace, selector, &list); err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			ep := fleetEndpoint{namespace: item.Metadata.Namespace, name: item.Metadata.Name}
+			if resource == "routes" {
+				if item.Spec.TLS == nil || item.Spec.Host == "" {
+					continue
+				}
+				ep.url = "https://" + item.Spec.Host + "/"
+			} else {
+				for _, p := range item.Spec.Ports {
+					if p.Name == "https" || p.Port == 443 {
+						ep.url = fmt.Sprintf("https://%s.%s.svc:%d/", ep.name, ep.namespace, p.Port)
+						break
+					}
+				}
+				if ep.url == "" {
+					continue
+				}
+			}
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints, nil
+}
+
+// probeFleet probes every discovered endpoint against the service account
+// CA and against system + service account CAs, at most concurrency at a
+// time and no faster than rate per second, and prints a per-namespace
+// report. Returns false if any endpoint is untrusted by both.
+func probeFleet(ctx context.Context, resource, selector, namespaceList string, concurrency int, rate float64) bool {
+	fmt.Fprintln(out, "=== Fleet Trust Audit ===")
+	fmt.Fprintln(out)
+
+	var namespaces []string
+	for _, ns := range strings.Split(namespaceList, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	endpoints, err := fleetEndpoints(ctx, resource, selector, namespaces)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: Cannot list %s: %v\n", resource, err)
+		return false
+	}
+	fmt.Fprintf(out, "Discovered %d HTTPS endpoints from %s (selector %q)\n\n", len(endpoints), resource, selector)
+	if len(endpoints) == 0 {
+		return true
+	}
+
+	saPool := x509.NewCertPool()
+	if caPEM, err := readServiceAccountCA(); err == nil {
+		saPool.AppendCertsFromPEM(caPEM)
+	}
+	unionPool, err := loadSystemCertPool(report)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  %v; system trust results will match the service account CA results\n\n", err)
+		unionPool = x509.NewCertPool()
+	}
+	if caPEM, err := readServiceAccountCA(); err == nil {
+		unionPool.AppendCertsFromPEM(caPEM)
+	}
+	probe := func(pool *x509.CertPool, target string) error {
+		client := &http.Client{
+			Timeout: timeout,
+			Transport: newProbeTransport(&tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			}),
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if rate <= 0 {
+		rate = 1
+	}
+	throttle := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer throttle.Stop()
+
+	type outcome struct {
+		saErr, unionErr error
+	}
+	outcomes := make([]outcome, len(endpoints))
+	jobs := make(chan int)
+	done := make(chan struct{})
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			for i := range jobs {
+				outcomes[i] = outcome{
+					saErr:    probe(saPool, endpoints[i].url),
+					unionErr: probe(unionPool, endpoints[i].url),
+				}
+			}
+			done <- struct{}{}
+		}()
+	}
+	// Stop handing out endpoints once ctx is cancelled; those in flight
+	// fail fast because their requests share ctx
+dispatch:
+	for i := range endpoints {
+		select {
+		case <-throttle.C:
+		case <-ctx.Done():
+			break dispatch
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	for w := 0; w < concurrency; w++ {
+		<-done
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	byNamespace := map[string][]int{}
+	var nsOrder []string
+	for i, ep := range endpoints {
+		if _, seen := byNamespace[ep.namespace]; !seen {
+			nsOrder = append(nsOrder, ep.namespace)
+		}
+		byNamespace[ep.namespace] = append(byNamespace[ep.namespace], i)
+	}
+	sort.Strings(nsOrder)
+
+	untrusted := 0
+	for _, ns := range nsOrder {
+		fmt.Fprintf(out, "--- Namespace %s ---\n", ns)
+		for _, i := range byNamespace[ns] {
+			ep, o := endpoints[i], outcomes[i]
+			switch {
+			case o.saErr == nil:
+				fmt.Fprintf(out, "✅ %s (%s): trusted via service account CA\n", ep.name, ep.url)
+			case o.unionErr == nil:
+				fmt.Fprintf(warnOut, "⚠️  %s (%s): trusted only with the system trust store\n", ep.name, ep.url)
+			default:
+				fmt.Fprintf(errOut, "❌ %s (%s): %v\n", ep.name, ep.url, o.unionErr)
+				untrusted++
+			}
+		}
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintf(out, "Endpoints probed: %d, untrusted: %d, namespaces: %d\n", len(endpoints), untrusted, len(nsOrder))
+	return untrusted == 0
+}
+
+// Signature schemes offered one at a time by probeSignatureSchemes, roughly
+// ordered from modern to legacy.
+var probedSignatureSchemes = []tls.SignatureScheme{
+	tls.ECDSAWithP256AndSHA256,
+	tls.ECDSAWithP384AndSHA384,
+	tls.ECDSAWithP521AndSHA512,
+	tls.Ed25519,
+	tls.PSSWithSHA256,
+	tls.PSSWithSHA384,
+	tls.PSSWithSHA512,
+	tls.PKCS1WithSHA256,
+	tls.PKCS1WithSHA384,
+	tls.PKCS1WithSHA512,
+	tls.PKCS1WithSHA1,
+	tls.ECDSAWithSHA1,
+}
+
+// ECDHE cipher suites offered in the probe ClientHello. Only ECDHE suites are
+// used because their ServerKeyExchange carries the signature scheme the
+// server picked.
+var probeCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+}
+
+// probeSignatureSchemes reports which handshake signature schemes the server
+// accepts. crypto/tls neither lets a client restrict the signature_algorithms
+// it offers nor exposes the scheme the server used, so this sends hand-built
+// TLS 1.2 ClientHellos offering a single scheme each and reads the scheme out
+// of the server's ServerKeyExchange. TLS 1.3 encrypts CertificateVerify, so
+// TLS 1.2 is the only version where this is observable on the wire.
+func probeSignatureSchemes(ctx context.Context, rawURL string) {
+	fmt.Fprintln(out, "--- Signature Algorithm Probe (TLS 1.2 ServerKeyExchange) ---")
+
+	addr, serverName, err := dialTarget(rawURL)
+	if err != nil {
+		fmt.Fprintf(errOut, "❌ FAIL: %v\n", err)
+		return
+	}
+	fmt.Fprintf(out, "Target: %s (SNI %s)\n\n", addr, serverName)
+
+	// Offer everything first to see what the server prefers
+	negotiated, err := offerSignatureSchemes(ctx, addr, serverName, probedSignatureSchemes)
+	if err != nil {
+		fmt.Fprintf(warnOut, "⚠️  Full offer failed: %v\n", err)
+	} else {
+		fmt.Fprintf(out, "Negotiated with full offer: %s\n", negotiated)
+	}
+	fmt.Fprintln(out)
+
+	accepted := 0
+	for _, scheme := range probedSignatureSchemes {
+		if ctx.Err() != nil {
+			return
+		}
+		got, err := offerSignatureSchemes(ctx, addr, serverName, []tls.SignatureScheme{scheme})
+		switch {
+		case err != nil:
+			fmt.Fprintf(errOut, "  ❌ %-24s rejected (%v)\n", scheme, err)
+		case got != scheme:
+			// A server that ignores the offered list is misbehaving; report
+			// what it actually used
+			fmt.Fprintf(warnOut, "  ⚠️  %-24s server ignored offer and used %s\n", scheme, got)
+		default:
+			fmt.Fprintf(out, "  ✅ %-24s accepted\n", scheme)
+			accepted++
+		}
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Accepted %d of %d offered signature schemes\n", accepted, len(probedSignatureSchemes))
+}
+
+// dialRaw opens a TCP connection to addr with a deadline of timeout for the
+// whole exchange. Cancelling ctx closes the connection, failing any read or
+// write in progress; call stop once done with it.
+func dialRaw(ctx context.Context, addr string) (conn net.Conn, stop func(), err error) {
+	conn, err = (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	return conn, func() {
+		stopClose()
+		conn.Close()
+	}, nil
+}
+
+// dialTarget turns an https URL into a host:port to dial and the SNI name.
+func dialTarget(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot parse URL %q: %v", rawURL, err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port), u.Hostname(), nil
+}
+
+// offerSignatureSchemes performs a partial TLS 1.2 handshake offering only
+// the given signature schemes and returns the scheme the server signed its
+// ServerKeyExchange with. An alert from the server is returned as an error.
+func offerSignatureSchemes(ctx context.Context, addr, serverName string, schemes []tls.SignatureScheme) (tls.SignatureScheme, error) {
+	conn, stop, err := dialRaw(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer stop()
+
+	hello, err := buildClientHello(serverName, schemes)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := conn.Write(hello); err != nil {
+		return 0, err
+	}
+
+	// Reassemble handshake messages across records until ServerKeyExchange
+	var handshake []byte
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return 0, fmt.Errorf("reading record: %v", err)
+		}
+		body := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return 0, fmt.Errorf("reading record: %v", err)
+		}
+
+		switch header[0] {
+		case 21: // alert
+			if len(body) >= 2 {
+				return 0, fmt.Errorf("alert: %s", alertDescription(body[1]))
+			}
+			return 0, errors.New("malformed alert")
+		case 22: // handshake
+			handshake = append(handshake, body...)
+		default:
+			return 0, fmt.Errorf("unexpected record type %d", header[0])
+		}
+
+		for len(handshake) >= 4 {
+			msgLen := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) < 4+msgLen {
+				break
+			}
+			msgType, msg := handshake[0], handshake[4:4+msgLen]
+			handshake = handshake[4+msgLen:]
+
+			switch msgType {
+			case 12: // ServerKeyExchange
+				return parseServerKeyExchangeScheme(msg)
+			case 14: // ServerHelloDone without a key exchange
+				return 0, errors.New("server did not send ServerKeyExchange")
+			}
+		}
+	}
+}
+
+// parseServerKeyExchangeScheme extracts the signature scheme from an ECDHE
+// ServerKeyExchange: curve_type(1) named_curve(2) point_len(1) point
+// signature_scheme(2) ...
+func parseServerKeyExchangeScheme(msg []byte) (tls.SignatureScheme, error) {
+	if len(msg) < 4 || msg[0] != 3 {
+		return 0, errors.New("unsupported ServerKeyExchange format")
+	}
+	offset := 4 + int(msg[3])
+	if len(msg) < offset+2 {
+		return 0, errors.New("truncated ServerKeyExchange")
+	}
+	return tls.SignatureScheme(binary.BigEndian.Uint16(msg[offset:])), nil
+}
+
+// buildClientHello encodes a TLS 1.2 ClientHello record offering the probe
+// cipher suites and the given signature schemes.
+func buildClientHello(serverName string, schemes []tls.SignatureScheme) ([]byte, error) {
+	var exts []byte
+
+	// server_name
+	sni := []byte{0}
+	sni = appendUint16Bytes(sni, []byte(serverName))
+	exts = appendExtension(exts, 0, appendUint16Bytes(nil, sni))
+
+	// supported_groups: x25519, P-256, P-384
+	groups := []byte{0x00, 0x1d, 0x00, 0x17, 0x00, 0x18}
+	exts = appendExtension(exts, 10, appendUint16Bytes(nil, groups))
+
+	// ec_point_formats: uncompressed
+	exts = appendExtension(exts, 11, []byte{1, 0})
+
+	// signature_algorithms
+	var sigs []byte
+	for _, s := range schemes {
+		sigs = binary.BigEndian.AppendUint16(sigs, uint16(s))
+	}
+	exts = appendExtension(exts, 13, appendUint16Bytes(nil, sigs))
+
+	// renegotiation_info, which some servers insist on
+	exts = appendExtension(exts, 0xff01, []byte{0})
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	var suites []byte
+	for _, cs := range probeCipherSuites {
+		suites = binary.BigEndian.AppendUint16(suites, cs)
+	}
+
+	body := []byte{0x03, 0x03}
+	body = append(body, random...)
+	body = append(body, 0) // empty session id
+	body = appendUint16Bytes(body, suites)
+	body = append(body, 1, 0) // null compression
+	body = appendUint16Bytes(body, exts)
+
+	msg := []byte{1, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	msg = append(msg, body...)
+
+	record := []byte{22, 0x03, 0x01}
+	return appendUint16Bytes(record, msg), nil
+}
+
+// alertDescription names the TLS alerts a server typically sends when it
+// rejects a ClientHello.
+func alertDescription(code byte) string {
+	switch code {
+	case 40:
+		return "handshake_failure"
+	case 47:
+		return "illegal_parameter"
+	case 70:
+		return "protocol_version"
+	case 71:
+		return "insufficient_security"
+	case 80:
+		return "internal_error"
+	case 112:
+		return "unrecognized_name"
+	}
+	return fmt.Sprintf("%d", code)
+}
+
+func appendExtension(b []byte, extType uint16, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, extType)
+	return appendUint16Bytes(b, data)
+}
+
+func appendUint16Bytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
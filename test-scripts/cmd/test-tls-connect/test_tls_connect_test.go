@@ -0,0 +1,801 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jctanner/odh-security-2.0/test-scripts/tlsprobe"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// discardReport silences the human-readable report for the rest of the
+// test.
+func discardReport(t *testing.T) {
+	t.Helper()
+	savedReport, savedOut, savedWarn, savedErr := report, out, warnOut, errOut
+	t.Cleanup(func() { report, out, warnOut, errOut = savedReport, savedOut, savedWarn, savedErr })
+	report, out, warnOut, errOut = io.Discard, io.Discard, io.Discard, io.Discard
+}
+
+// writeTestCA writes a self-signed CA certificate as PEM and returns its path.
+func writeTestCA(t *testing.T, dir string) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fallback Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "fallback.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSystemCertPool(t *testing.T) {
+	dir := t.TempDir()
+	validFallback := writeTestCA(t, dir)
+	emptyFallback := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFallback, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	systemPool := x509.NewCertPool()
+	available := func() (*x509.CertPool, error) { return systemPool, nil }
+	unavailable := func() (*x509.CertPool, error) { return nil, errors.New("stubbed: no system roots") }
+
+	tests := []struct {
+		name            string
+		loader          func() (*x509.CertPool, error)
+		fallback        string
+		wantUnavailable bool
+		wantSystemPool  bool
+	}{
+		{name: "platform store available", loader: available, wantSystemPool: true},
+		{name: "platform store available ignores fallback", loader: available, fallback: validFallback, wantSystemPool: true},
+		{name: "unavailable without fallback", loader: unavailable, wantUnavailable: true},
+		{name: "unavailable with fallback", loader: unavailable, fallback: validFallback},
+		{name: "unavailable with missing fallback", loader: unavailable, fallback: filepath.Join(dir, "missing.pem"), wantUnavailable: true},
+		{name: "unavailable with empty fallback", loader: unavailable, fallback: emptyFallback, wantUnavailable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLoader, oldFallback := systemCertPoolLoader, systemCAFallback
+			defer func() { systemCertPoolLoader, systemCAFallback = oldLoader, oldFallback }()
+			systemCertPoolLoader = tt.loader
+			systemCAFallback = tt.fallback
+
+			var w strings.Builder
+			pool, err := loadSystemCertPool(&w)
+
+			if tt.wantUnavailable {
+				if !errors.Is(err, errSystemTrustUnavailable) {
+					t.Fatalf("expected errSystemTrustUnavailable, got %v", err)
+				}
+				if pool != nil {
+					t.Fatalf("expected no pool when unavailable")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantSystemPool && pool != systemPool {
+				t.Fatalf("expected the platform pool to be returned")
+			}
+			if !tt.wantSystemPool && pool == systemPool {
+				t.Fatalf("expected the fallback pool, got the platform pool")
+			}
+			if usedFallback := strings.Contains(w.String(), "using fallback"); usedFallback == tt.wantSystemPool {
+				t.Fatalf("fallback warning written = %v, want %v (output %q)", usedFallback, !tt.wantSystemPool, w.String())
+			}
+		})
+	}
+}
+
+func TestWarnIfSystemPoolSparse(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" || runtime.GOOS == "windows" {
+		t.Skip("platform trust store cannot be enumerated")
+	}
+	certPEM, err := os.ReadFile(writeTestCA(t, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := func(n int) func() (*x509.CertPool, error) {
+		return func() (*x509.CertPool, error) {
+			p := x509.NewCertPool()
+			for i := 0; i < n; i++ {
+				// AddCert dedups on the raw bytes, so make each copy distinct
+				c := *cert
+				c.Raw = append([]byte{byte(i)}, cert.Raw...)
+				p.AddCert(&c)
+			}
+			return p, nil
+		}
+	}
+
+	tests := []struct {
+		name   string
+		loader func() (*x509.CertPool, error)
+		want   string
+	}{
+		{"empty store", pool(0), "EMPTY"},
+		{"sparse store", pool(3), "only 3 certificates"},
+		{"populated store", pool(minSystemRoots), ""},
+		{"unavailable store", func() (*x509.CertPool, error) { return nil, errors.New("stubbed") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLoader := systemCertPoolLoader
+			defer func() { systemCertPoolLoader = oldLoader }()
+			systemCertPoolLoader = tt.loader
+
+			var buf strings.Builder
+			warnIfSystemPoolSparse(&buf)
+			got := buf.String()
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("unexpected warning: %q", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) || !strings.Contains(got, "ca-certificates") {
+				t.Errorf("warning = %q, want it to mention %q and ca-certificates", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustVerdict(t *testing.T) {
+	tests := []struct {
+		name                                string
+		serviceAccountCA, union, systemOnly string
+		want                                string
+	}{
+		{"default works", "success", "success", "fail", "no change needed"},
+		{"trusted everywhere", "success", "success", "success", "no change needed"},
+		{"needs system trust store", "fail", "success", "success", "--use-system-trust-store=true"},
+		{"system store unavailable", "fail", "skipped", "skipped", "unknown"},
+		{"union flaked", "fail", "fail", "success", "rerun"},
+		{"trusted nowhere", "fail", "fail", "fail", "add its root CA"},
+		{"no results", "", "", "", "Not enough"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trustVerdict(tt.serviceAccountCA, tt.union, tt.systemOnly); !strings.Contains(got, tt.want) {
+				t.Errorf("trustVerdict(%q, %q, %q) = %q, want it to mention %q", tt.serviceAccountCA, tt.union, tt.systemOnly, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadKubeAPIKubeconfig(t *testing.T) {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: %s
+clusters:
+- name: test
+  cluster:
+    server: https://api.example.com:6443/
+    insecure-skip-tls-verify: true
+contexts:
+- name: token
+  context: {cluster: test, user: token}
+- name: exec
+  context: {cluster: test, user: exec}
+users:
+- name: token
+  user:
+    token: sha256~kubeconfig
+- name: exec
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: example-credential-plugin
+      interactiveMode: Never
+`
+	tests := []struct {
+		name       string
+		context    string
+		token      string
+		wantToken  string
+		wantExec   bool
+		wantSource string
+	}{
+		{name: "token user", context: "token", wantToken: "sha256~kubeconfig", wantSource: `kubeconfig context "token"`},
+		{name: "exec plugin", context: "exec", wantExec: true, wantSource: `kubeconfig context "exec"`},
+		{name: "override replaces exec plugin", context: "exec", token: "sha256~override", wantToken: "sha256~override", wantSource: `kubeconfig context "exec", token from -token`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kubeconfig")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(kubeconfig, tt.context)), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			oldPath, oldToken, oldCached := kubeconfigPath, bearerToken, cachedKubeAPI
+			defer func() { kubeconfigPath, bearerToken, cachedKubeAPI = oldPath, oldToken, oldCached }()
+			kubeconfigPath, bearerToken, cachedKubeAPI = path, tt.token, nil
+
+			api, err := loadKubeAPI()
+			if err != nil {
+				t.Fatalf("loadKubeAPI() error = %v", err)
+			}
+			if api.server != "https://api.example.com:6443" {
+				t.Errorf("server = %q, want https://api.example.com:6443", api.server)
+			}
+			if api.source != tt.wantSource {
+				t.Errorf("source = %q, want %q", api.source, tt.wantSource)
+			}
+			if api.token != tt.wantToken || api.config.BearerToken != tt.wantToken {
+				t.Errorf("token = %q, config token = %q, want %q", api.token, api.config.BearerToken, tt.wantToken)
+			}
+			if gotExec := api.config.ExecProvider != nil; gotExec != tt.wantExec {
+				t.Errorf("exec provider set = %v, want %v", gotExec, tt.wantExec)
+			}
+			if !api.config.Insecure {
+				t.Errorf("insecure-skip-tls-verify was not applied")
+			}
+		})
+	}
+}
+
+// useFakeKube serves API objects from a fake dynamic client for the rest
+// of the test.
+func useFakeKube(t *testing.T, objects ...k8sruntime.Object) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(k8sruntime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMapsResource: "ConfigMapList",
+		routesResource:     "RouteList",
+		servicesResource:   "ServiceList",
+	}, objects...)
+	old := kubeDynamicClient
+	kubeDynamicClient = func() (dynamic.Interface, error) { return client, nil }
+	t.Cleanup(func() { kubeDynamicClient = old })
+	return client
+}
+
+// kubeObject builds an API object for the fake client.
+func kubeObject(apiVersion, kind, namespace, name string, labels map[string]interface{}, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "labels": labels},
+	}
+	if namespace != "" {
+		obj["metadata"].(map[string]interface{})["namespace"] = namespace
+	}
+	for k, v := range fields {
+		obj[k] = v
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestGetConfigMap(t *testing.T) {
+	useFakeKube(t, kubeObject("v1", "ConfigMap", "openshift-config", "user-ca-bundle", nil, map[string]interface{}{
+		"data": map[string]interface{}{"ca-bundle.crt": "PEM"},
+	}))
+
+	cm, err := getConfigMap(context.Background(), "openshift-config/user-ca-bundle")
+	if err != nil {
+		t.Fatalf("getConfigMap() error = %v", err)
+	}
+	if cm.Data["ca-bundle.crt"] != "PEM" {
+		t.Errorf("data = %v, want ca-bundle.crt=PEM", cm.Data)
+	}
+	if _, err := getConfigMap(context.Background(), "openshift-config/missing"); !errors.Is(err, errKubeNotFound) {
+		t.Errorf("getConfigMap() of a missing ConfigMap error = %v, want errKubeNotFound", err)
+	}
+	if _, err := getConfigMap(context.Background(), "no-namespace"); err == nil {
+		t.Errorf("getConfigMap() accepted a ref without a namespace")
+	}
+}
+
+func TestKubeGetWebhookConfiguration(t *testing.T) {
+	caPEM, err := os.ReadFile(writeTestCA(t, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useFakeKube(t, kubeObject("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", "", "checks", nil, map[string]interface{}{
+		"webhooks": []interface{}{map[string]interface{}{
+			"name": "check.example.com",
+			"clientConfig": map[string]interface{}{
+				"caBundle": base64.StdEncoding.EncodeToString(caPEM),
+				"service":  map[string]interface{}{"namespace": "team-a", "name": "webhook", "port": int64(9443)},
+			},
+		}},
+	}))
+
+	var config webhookConfiguration
+	if err := kubeGet(context.Background(), webhookConfigurationsResource("validatingwebhookconfigurations"), "", "checks", &config); err != nil {
+		t.Fatalf("kubeGet() error = %v", err)
+	}
+	if len(config.Webhooks) != 1 {
+		t.Fatalf("got %d webhooks, want 1", len(config.Webhooks))
+	}
+	cc := config.Webhooks[0].ClientConfig
+	if string(cc.CABundle) != string(caPEM) {
+		t.Errorf("caBundle was not decoded from base64")
+	}
+	if cc.Service == nil || cc.Service.Port == nil || *cc.Service.Port != 9443 {
+		t.Errorf("service = %+v, want port 9443", cc.Service)
+	}
+	if cc.URL != nil {
+		t.Errorf("url = %q, want unset", *cc.URL)
+	}
+}
+
+func TestReadTrustLocation(t *testing.T) {
+	caPEM, err := os.ReadFile(writeTestCA(t, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useFakeKube(t,
+		kubeObject("v1", "Secret", "team-a", "tls", nil, map[string]interface{}{
+			"data": map[string]interface{}{"ca.crt": base64.StdEncoding.EncodeToString(caPEM)},
+		}),
+		kubeObject("v1", "ConfigMap", "team-a", "trusted-ca", nil, map[string]interface{}{
+			"data": map[string]interface{}{"ca-bundle.crt": string(caPEM)},
+		}),
+	)
+
+	tests := []struct {
+		name    string
+		loc     trustLocation
+		wantErr bool
+	}{
+		{name: "secret", loc: trustLocation{kind: "secret", namespace: "team-a", name: "tls", key: "ca.crt"}},
+		{name: "configmap", loc: trustLocation{kind: "configmap", namespace: "team-a", name: "trusted-ca", key: "ca-bundle.crt"}},
+		{name: "missing key", loc: trustLocation{kind: "secret", namespace: "team-a", name: "tls", key: "tls.crt"}, wantErr: true},
+		{name: "missing secret", loc: trustLocation{kind: "secret", namespace: "team-a", name: "other", key: "ca.crt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, err := readTrustLocation(context.Background(), tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readTrustLocation() returned %d certificates, want an error", len(certs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readTrustLocation() error = %v", err)
+			}
+			if len(certs) != 1 || certs[0].Subject.CommonName != "Fallback Test Root" {
+				t.Errorf("readTrustLocation() = %d certificates, want the test CA", len(certs))
+			}
+		})
+	}
+}
+
+func TestAuthTrustTargets(t *testing.T) {
+	discardReport(t)
+
+	useFakeKube(t,
+		kubeObject("config.openshift.io/v1", "Authentication", "", "cluster", nil, map[string]interface{}{
+			"spec": map[string]interface{}{},
+		}),
+		kubeObject("config.openshift.io/v1", "OAuth", "", "cluster", nil, map[string]interface{}{
+			"spec": map[string]interface{}{"identityProviders": []interface{}{
+				map[string]interface{}{"name": "htpasswd", "type": "HTPasswd"},
+				map[string]interface{}{"name": "sso", "type": "OpenID", "openID": map[string]interface{}{
+					"issuer": "https://sso.example.com",
+					"ca":     map[string]interface{}{"name": "sso-ca"},
+				}},
+			}},
+		}),
+	)
+
+	got, err := authTrustTargets(context.Background())
+	if err != nil {
+		t.Fatalf("authTrustTargets() error = %v", err)
+	}
+	want := []authTrustTarget{{
+		name:      "sso",
+		source:    "oauth/cluster spec.identityProviders[1].openID.ca",
+		issuerURL: "https://sso.example.com",
+		caRef:     "openshift-config/sso-ca",
+		caKey:     "ca.crt",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("authTrustTargets() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteResultsConfigMap(t *testing.T) {
+	existing := kubeObject("v1", "ConfigMap", "team-a", "tls-audit", map[string]interface{}{"team": "a"}, map[string]interface{}{
+		"data": map[string]interface{}{"notes": "kept", "status": "stale"},
+	})
+	existing.SetAnnotations(map[string]string{"owner": "platform"})
+
+	tests := []struct {
+		name      string
+		objects   []k8sruntime.Object
+		conflicts int
+		wantData  map[string]string
+		wantLabel map[string]string
+	}{
+		{
+			name:      "created",
+			wantLabel: map[string]string{"app.kubernetes.io/name": "tls-trust-audit"},
+		},
+		{
+			name:      "merged into an existing ConfigMap",
+			objects:   []k8sruntime.Object{existing.DeepCopy()},
+			wantData:  map[string]string{"notes": "kept"},
+			wantLabel: map[string]string{"app.kubernetes.io/name": "tls-trust-audit", "team": "a"},
+		},
+		{
+			name:      "retried after a conflicting write",
+			objects:   []k8sruntime.Object{existing.DeepCopy()},
+			conflicts: 2,
+			wantData:  map[string]string{"notes": "kept"},
+			wantLabel: map[string]string{"app.kubernetes.io/name": "tls-trust-audit", "team": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := useFakeKube(t, tt.objects...)
+			updates := 0
+			client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+				updates++
+				if updates <= tt.conflicts {
+					return true, nil, apierrors.NewConflict(configMapsResource.GroupResource(), "tls-audit", errors.New("modified concurrently"))
+				}
+				return false, nil, nil
+			})
+
+			if err := writeResultsConfigMap(context.Background(), "team-a/tls-audit", "https://oauth.example.com"); err != nil {
+				t.Fatalf("writeResultsConfigMap() error = %v", err)
+			}
+			obj, err := client.Resource(configMapsResource).Namespace("team-a").Get(context.Background(), "tls-audit", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+			if data["targets"] != "https://oauth.example.com" || data["status"] != "success" || data["timestamp"] == "" {
+				t.Errorf("data = %v, want this run's targets, status and timestamp", data)
+			}
+			for key, value := range tt.wantData {
+				if data[key] != value {
+					t.Errorf("data.%s = %q, want %q", key, data[key], value)
+				}
+			}
+			if !reflect.DeepEqual(obj.GetLabels(), tt.wantLabel) {
+				t.Errorf("labels = %v, want %v", obj.GetLabels(), tt.wantLabel)
+			}
+			if len(tt.objects) > 0 && obj.GetAnnotations()["owner"] != "platform" {
+				t.Errorf("annotations = %v, want owner=platform kept", obj.GetAnnotations())
+			}
+			if tt.conflicts > 0 && updates != tt.conflicts+1 {
+				t.Errorf("%d update attempts, want %d", updates, tt.conflicts+1)
+			}
+		})
+	}
+}
+
+func TestReconcileOnce(t *testing.T) {
+	discardReport(t)
+
+	client := useFakeKube(t, kubeObject("v1", "ConfigMap", "team-a", "other", nil, nil))
+	configMaps := client.Resource(configMapsResource).Namespace("team-a")
+	source := "file:" + writeTestCA(t, t.TempDir())
+	get := func() *unstructured.Unstructured {
+		t.Helper()
+		obj, err := configMaps.Get(context.Background(), "trusted-ca", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+
+	if err := reconcileOnce(context.Background(), []string{source}, "team-a/trusted-ca"); err != nil {
+		t.Fatalf("reconcileOnce() creating error = %v", err)
+	}
+	created, _, _ := unstructured.NestedString(get().Object, "data", "ca-bundle.crt")
+	if !strings.Contains(created, "BEGIN CERTIFICATE") {
+		t.Fatalf("created bundle = %q, want a certificate", created)
+	}
+
+	obj := get()
+	unstructured.SetNestedField(obj.Object, "stale", "data", "ca-bundle.crt")
+	unstructured.SetNestedField(obj.Object, "kept", "data", "other-key")
+	if _, err := configMaps.Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcileOnce(context.Background(), []string{source}, "team-a/trusted-ca"); err != nil {
+		t.Fatalf("reconcileOnce() updating error = %v", err)
+	}
+	obj = get()
+	if got, _, _ := unstructured.NestedString(obj.Object, "data", "ca-bundle.crt"); got != created {
+		t.Errorf("updated bundle = %q, want %q", got, created)
+	}
+	if kept, _, _ := unstructured.NestedString(obj.Object, "data", "other-key"); kept != "kept" {
+		t.Errorf("other-key = %q after the update, want it preserved", kept)
+	}
+}
+
+// TestReconcileTrustBundleCancel checks that cancelling ctx ends the
+// reconcile loop instead of sleeping out the interval.
+func TestReconcileTrustBundleCancel(t *testing.T) {
+	discardReport(t)
+
+	useFakeKube(t)
+	source := "file:" + writeTestCA(t, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan bool)
+	go func() { done <- reconcileTrustBundle(ctx, []string{source}, "team-a/trusted-ca", time.Hour) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconcileTrustBundle() kept looping after ctx was cancelled")
+	}
+}
+
+func TestFleetEndpoints(t *testing.T) {
+	audited := map[string]interface{}{"audit": "true"}
+	useFakeKube(t,
+		kubeObject("route.openshift.io/v1", "Route", "team-a", "secure", audited, map[string]interface{}{
+			"spec": map[string]interface{}{"host": "secure.apps.example.com", "tls": map[string]interface{}{"termination": "edge"}},
+		}),
+		kubeObject("route.openshift.io/v1", "Route", "team-a", "plain", audited, map[string]interface{}{
+			"spec": map[string]interface{}{"host": "plain.apps.example.com"},
+		}),
+		kubeObject("route.openshift.io/v1", "Route", "team-b", "unlabelled", nil, map[string]interface{}{
+			"spec": map[string]interface{}{"host": "other.apps.example.com", "tls": map[string]interface{}{"termination": "edge"}},
+		}),
+		kubeObject("v1", "Service", "team-b", "api", audited, map[string]interface{}{
+			"spec": map[string]interface{}{"ports": []interface{}{
+				map[string]interface{}{"name": "metrics", "port": int64(9090)},
+				map[string]interface{}{"name": "https", "port": int64(8443)},
+			}},
+		}),
+		kubeObject("v1", "Service", "team-b", "http-only", audited, map[string]interface{}{
+			"spec": map[string]interface{}{"ports": []interface{}{map[string]interface{}{"name": "http", "port": int64(80)}}},
+		}),
+	)
+
+	tests := []struct {
+		name       string
+		resource   string
+		selector   string
+		namespaces []string
+		want       []fleetEndpoint
+	}{
+		{
+			name:     "routes with TLS cluster-wide",
+			resource: "routes",
+			want: []fleetEndpoint{
+				{namespace: "team-a", name: "secure", url: "https://secure.apps.example.com/"},
+				{namespace: "team-b", name: "unlabelled", url: "https://other.apps.example.com/"},
+			},
+		},
+		{
+			name:     "routes matching a selector",
+			resource: "routes",
+			selector: "audit=true",
+			want:     []fleetEndpoint{{namespace: "team-a", name: "secure", url: "https://secure.apps.example.com/"}},
+		},
+		{
+			name:       "routes in other namespaces",
+			resource:   "routes",
+			namespaces: []string{"team-c"},
+		},
+		{
+			name:       "services with an https port",
+			resource:   "services",
+			namespaces: []string{"team-b"},
+			want:       []fleetEndpoint{{namespace: "team-b", name: "api", url: "https://api.team-b.svc:8443/"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fleetEndpoints(context.Background(), tt.resource, tt.selector, tt.namespaces)
+			if err != nil {
+				t.Fatalf("fleetEndpoints() error = %v", err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].namespace+"/"+got[i].name < got[j].namespace+"/"+got[j].name })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fleetEndpoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeRecords(t *testing.T) {
+	savedLogger, savedLevel, savedResults := logger, logLevel, results
+	defer func() { logger, logLevel, results = savedLogger, savedLevel, savedResults }()
+	var buf bytes.Buffer
+	logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logLevel = slog.LevelInfo
+	results = nil
+
+	recordSuccess("system-only", "https://oauth.example.com", &tlsprobe.ProbeResult{
+		StatusCode: 403,
+		TLSVersion: tls.VersionTLS13,
+		Handshake:  25 * time.Millisecond,
+	})
+	recordResult("service-account-ca", "https://oauth.example.com", "fail", "x509: certificate signed by unknown authority", 0)
+	recordResult("system-and-service-account-ca", "https://oauth.example.com", "skipped", "system trust store unavailable", 0)
+
+	want := []map[string]any{
+		{"level": "INFO", "msg": "probe result", "scenario": "system-only", "status": "success", "http_status": 403.0, "tls_version": "TLS 1.3", "handshake_ms": 25.0},
+		{"level": "ERROR", "msg": "probe result", "scenario": "service-account-ca", "status": "fail", "error": "x509: certificate signed by unknown authority"},
+		{"level": "WARN", "msg": "probe result", "scenario": "system-and-service-account-ca", "status": "skipped", "error": "system trust store unavailable"},
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d records, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record %d is not JSON: %v", i, err)
+		}
+		if record["target"] != "https://oauth.example.com" {
+			t.Errorf("record %d target = %v, want https://oauth.example.com", i, record["target"])
+		}
+		for key, value := range want[i] {
+			if record[key] != value {
+				t.Errorf("record %d %s = %v, want %v", i, key, record[key], value)
+			}
+		}
+	}
+
+	// Records below -log-level are dropped; the results are still kept
+	buf.Reset()
+	logLevel = slog.LevelError
+	recordSuccess("insecure", "https://oauth.example.com", &tlsprobe.ProbeResult{StatusCode: 200})
+	if buf.Len() != 0 {
+		t.Errorf("info record written at -log-level=error: %s", buf.String())
+	}
+	if len(results) != 4 {
+		t.Errorf("recorded %d results, want 4", len(results))
+	}
+}
+
+// TestWriteJSONReport pins the -json document keys that consumers read.
+func TestWriteJSONReport(t *testing.T) {
+	savedLogger, savedResults := logger, results
+	defer func() { logger, results = savedLogger, savedResults }()
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	results = nil
+
+	target := "https://oauth.example.com"
+	notAfter := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordSuccess("system-only", target, &tlsprobe.ProbeResult{
+		StatusCode:       403,
+		TLSVersion:       tls.VersionTLS13,
+		PeerCertificates: []*x509.Certificate{{NotAfter: notAfter}},
+	})
+	recordResult("service-account-ca", target, "fail", "x509: certificate signed by unknown authority", 0)
+
+	var buf bytes.Buffer
+	if err := writeJSONReport(&buf, []string{target}, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		SchemaVersion *int  `json:"schemaVersion"`
+		Success       *bool `json:"success"`
+		Targets       []struct {
+			URL       string           `json:"url"`
+			Scenarios []map[string]any `json:"scenarios"`
+		} `json:"targets"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not JSON: %v\n%s", err, buf.String())
+	}
+	if doc.SchemaVersion == nil || *doc.SchemaVersion != jsonSchemaVersion {
+		t.Errorf("schemaVersion = %v, want %d", doc.SchemaVersion, jsonSchemaVersion)
+	}
+	if doc.Success == nil || *doc.Success {
+		t.Errorf("success = %v, want false", doc.Success)
+	}
+	if len(doc.Targets) != 1 || doc.Targets[0].URL != target {
+		t.Fatalf("targets = %+v, want one for %s", doc.Targets, target)
+	}
+	want := []map[string]any{
+		{"scenario": "system-only", "result": "success", "success": true, "httpStatus": 403.0,
+			"tlsVersion": "TLS 1.3", "peerCertNotAfter": "2026-03-01T12:00:00Z"},
+		{"scenario": "service-account-ca", "result": "fail", "success": false,
+			"error": "x509: certificate signed by unknown authority"},
+	}
+	if got := doc.Targets[0].Scenarios; !reflect.DeepEqual(got, want) {
+		t.Errorf("scenarios = %v, want %v", got, want)
+	}
+
+	if err := writeJSONReport(failingWriter{}, []string{target}, nil, false); err == nil {
+		t.Error("write to a failing writer: want error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+// TestReportLevels checks that -log-level filters the human report by the
+// level each line is written at: at error, a failure keeps its "→" detail
+// lines, and an unterminated last line is not held back.
+func TestReportLevels(t *testing.T) {
+	savedReport, savedOut, savedWarn, savedErr, savedLevel := report, out, warnOut, errOut, logLevel
+	defer func() {
+		report, out, warnOut, errOut, logLevel = savedReport, savedOut, savedWarn, savedErr, savedLevel
+	}()
+
+	for _, tt := range []struct {
+		level string
+		quiet bool
+		want  string
+	}{
+		{"info", false, "--- header ---\n⚠️  slow\n❌ FAIL: untrusted\n   → TLS validation failed\nno newline"},
+		{"warn", false, "⚠️  slow\n❌ FAIL: untrusted\n   → TLS validation failed\n"},
+		{"info", true, "❌ FAIL: untrusted\n   → TLS validation failed\n"},
+	} {
+		t.Run(fmt.Sprintf("%s quiet=%v", tt.level, tt.quiet), func(t *testing.T) {
+			if err := configureLogging("human", tt.level, tt.quiet); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			report, out, warnOut, errOut = &buf, at(&buf, slog.LevelInfo), at(&buf, slog.LevelWarn), at(&buf, slog.LevelError)
+
+			fmt.Fprintln(out, "--- header ---")
+			fmt.Fprintln(warnOut, "⚠️  slow")
+			fmt.Fprintln(errOut, "❌ FAIL: untrusted")
+			printProbeFailure(report, errors.New("x509: unknown authority"), "TLS validation failed")
+			fmt.Fprint(out, "no newline")
+			if got := buf.String(); got != tt.want {
+				t.Errorf("report = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,2393 @@
+package main
+
+import (
+	"bytes"
+	"crypto/fips140"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// out receives the human-readable report. It is discarded when another
+// output format is selected.
+var out io.Writer = os.Stdout
+
+func main() {
+	output := flag.String("output", "text", "output format: text, sarif, mermaid or tree")
+	mermaid := flag.Bool("mermaid", false, "shorthand for -output mermaid: emit a Mermaid graph of the trust chain")
+	tree := flag.Bool("tree", false, "shorthand for -output tree: print each root with the intermediates and leaves it anchors nested beneath it, then the orphans")
+	publicRoots := flag.Bool("public-roots", false, "classify the bundle's roots as publicly trusted or not using the embedded snapshot, independent of the local system store")
+	publicRootsFile := flag.String("public-roots-file", "", "-public-roots: `file` of SHA-256 fingerprints (one per line, optional subject after) to use instead of the embedded snapshot")
+	score := flag.Bool("score", false, "compute a 0-100 trust quality score for the bundle from weighted factors")
+	internalRoots := flag.String("internal-roots", "", "-score: `file` of fingerprints of known internal roots, in -public-roots-file format")
+	issuedBy := flag.Bool("verify-issued-by", false, "only check that <parent-cert> signed <child-cert>, given as the two arguments")
+	checkOCSP := flag.Bool("check-ocsp", false, "query each certificate's OCSP responder (from AuthorityInfoAccess) and report Good, Revoked or Unknown")
+	crlFile := flag.String("crl", "", "check the bundle's certificates against this CRL `file` (PEM or DER), verifying its signature with the issuing CA from the bundle")
+	var expectedRoots rootNames
+	flag.Var(&expectedRoots, "expected-root", "CommonName of a root the bundle's intermediates should chain to (repeatable; default \"ISRG Root X1\")")
+	connect := flag.String("connect", "", "analyze the chain a live server presents at `host:port` instead of a bundle file (port defaults to 443; bracket IPv6 literals, e.g. [::1]:8443)")
+	serverName := flag.String("sni", "", "-connect: server name to send in the TLS ClientHello (default: the host from -connect)")
+	timeout := flag.Duration("timeout", 10*time.Second, "-connect: timeout for the connection and handshake")
+	bundleFile := flag.String("bundle", "", "CA bundle `file` of intermediates and roots, as an alternative to the <ca-bundle-file> argument (with -connect: the roots to verify the server's chain against instead of the system store)")
+	var leafFiles fileList
+	outputChain := flag.String("output-chain", "", "after verification succeeds, write the chain it built, leaf to root, as PEM to this `file` (needs exactly one verifiable chain; pick it with -leaf)")
+	flag.Var(&leafFiles, "leaf", "leaf certificate `file` to verify against the bundle instead of analyzing the bundle itself (repeatable; extra certs in the file are used as intermediates)")
+	validatorNames := flag.String("validators", "", "comma-separated custom `validators` to run after the standard analysis, or \"list\" to show them")
+	flag.Usage = func() {
+		fmt.Println("Usage: go run ./cmd/verify-root-ca [flags] <ca-bundle-file>")
+		fmt.Println("       go run ./cmd/verify-root-ca [flags] -connect <host:port>")
+		fmt.Println("       go run ./cmd/verify-root-ca -bundle <ca-bundle-file> -leaf <leaf-cert> [-leaf <leaf-cert> ...]")
+		fmt.Println("       go run ./cmd/verify-root-ca -verify-issued-by <child-cert> <parent-cert>")
+		fmt.Println("Use - as <ca-bundle-file> to read the bundle from stdin")
+		fmt.Println("This tool verifies if ISRG Root X1 (Let's Encrypt root) is present as a trusted root")
+		fmt.Println("and that every chain in the bundle builds to a self-signed root in the bundle")
+		fmt.Println()
+		fmt.Println("Flags:")
+		flag.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Exit codes:")
+		fmt.Println("  0  every chain is complete and valid, with no error-level findings")
+		fmt.Println("  1  an intermediate issued by an expected root is present but that root is missing,")
+		fmt.Println("     e.g. a Let's Encrypt intermediate without ISRG Root X1 (also usage and file errors)")
+		fmt.Println("  2  a certificate in the bundle could not be parsed")
+		fmt.Println("  3  a Let's Encrypt intermediate in the bundle has expired")
+		fmt.Println("  4  a certificate does not verify to a root in the bundle (with -connect, or in the trust store)")
+		fmt.Println("  5  a chain is longer than a CA's path length constraint allows")
+		fmt.Println("  6  any other error-level finding, such as a revoked certificate (-check-ocsp, -crl),")
+		fmt.Println("     a server certificate that does not match its name (-connect) or a failed error-level validator")
+		fmt.Println("  When several apply, the first in the order 2, 1, 3, 4, 5, 6 is used")
+	}
+	flag.Parse()
+
+	if *issuedBy {
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if !verifyIssuedBy(flag.Arg(0), flag.Arg(1)) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *validatorNames == "list" {
+		listValidators()
+		return
+	}
+	enabled, err := lookupValidators(*validatorNames)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if flag.NArg() < 1 && *connect == "" && *bundleFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *mermaid {
+		*output = "mermaid"
+	}
+	if *tree {
+		*output = "tree"
+	}
+
+	if len(leafFiles) > 0 && *output != "text" {
+		fmt.Println("Error: -leaf only supports -output text")
+		os.Exit(1)
+	}
+
+	if len(expectedRoots) == 0 {
+		expectedRoots = rootNames{"ISRG Root X1"}
+	}
+
+	switch *output {
+	case "text":
+	case "sarif", "mermaid", "tree":
+		// These are rendered at the end; suppress the human-readable
+		// report so stdout is a single document
+		out = io.Discard
+	default:
+		fmt.Printf("Unknown output format %q (expected text, sarif, mermaid or tree)\n", *output)
+		os.Exit(1)
+	}
+
+	caFile := flag.Arg(0)
+	if *bundleFile != "" {
+		caFile = *bundleFile
+	}
+
+	// Read the CA bundle file, or the chain a live server presents
+	var caData []byte
+	var trustStore *x509.CertPool
+	var connectFindings []finding
+	if *connect != "" {
+		trustStore, err = connectTrustStore(*bundleFile)
+		if err != nil {
+			fmt.Printf("Error loading roots for -connect: %v\n", err)
+			os.Exit(1)
+		}
+		caFile = *connect
+		caData, connectFindings, err = fetchServerChain(*connect, *serverName, *timeout)
+		if err != nil {
+			fmt.Printf("Error connecting to %s: %v\n", *connect, err)
+			os.Exit(1)
+		}
+	} else {
+		caData, err = readBundle(caFile)
+		if err != nil {
+			fmt.Printf("Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// With -leaf the bundle is only the trust material for the leaves; the
+	// bundle analysis below is for when the bundle is the subject
+	if len(leafFiles) > 0 {
+		bundleCerts, err := parseCerts(caData)
+		if err != nil {
+			fmt.Printf("Error parsing %s: %v\n", caFile, err)
+			os.Exit(exitParseError)
+		}
+		chains, ok := verifyLeaves(bundleCerts, leafFiles, time.Now())
+		if *outputChain != "" {
+			if err := writeChainFile(*outputChain, chains, ok); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing -output-chain: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(out, "✅ Verified chain written to %s\n", *outputChain)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A file with no PEM block at all may be binary DER, as exported by
+	// Windows and Java tooling
+	encoding := "PEM"
+	if block, _ := pem.Decode(caData); block == nil && len(bytes.TrimSpace(caData)) > 0 {
+		encoding = "DER"
+	}
+
+	fmt.Fprintf(out, "=== Verifying Certificate Trust Chain ===\n")
+	fmt.Fprintf(out, "Crypto backend: %s\n", cryptoBackend())
+	fmt.Fprintf(out, "Encoding: %s\n\n", encoding)
+	
+	// Track what we find
+	parseErrors := 0
+	
+	findings := connectFindings
+	var certs []*x509.Certificate
+	rest := caData
+	certCount := 0
+	now := time.Now()
+
+	// analyze runs the per-certificate checks; line is where the cert
+	// starts in the file, or 0 for DER input
+	analyze := func(cert *x509.Certificate, line int) {
+		certCount++
+		certs = append(certs, cert)
+
+		if now.After(cert.NotAfter) {
+			fmt.Fprintf(out, "⚠️  Certificate #%d (%s) expired on %s\n\n", certCount, cert.Subject.String(), cert.NotAfter.Format("2006-01-02"))
+			findings = append(findings, finding{ruleID: "expired-cert", certIndex: certCount, line: line,
+				message: fmt.Sprintf("Certificate %s expired on %s", cert.Subject.String(), cert.NotAfter.Format("2006-01-02"))})
+		}
+
+		switch cert.SignatureAlgorithm {
+		case x509.MD5WithRSA, x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+			// Self-signed roots are trusted by identity, not by their
+			// signature, so a weak self-signature does not matter
+			if cert.Subject.String() != cert.Issuer.String() {
+				fmt.Fprintf(out, "⚠️  Certificate #%d (%s) uses weak signature algorithm %s\n\n", certCount, cert.Subject.String(), cert.SignatureAlgorithm)
+				findings = append(findings, finding{ruleID: "weak-signature", certIndex: certCount, line: line,
+					message: fmt.Sprintf("Certificate %s is signed with weak algorithm %s", cert.Subject.String(), cert.SignatureAlgorithm)})
+			}
+		}
+
+		// Critical extensions Go's parser did not process; strict verifiers
+		// (including Go's own) reject the cert even though it parsed
+		if len(cert.UnhandledCriticalExtensions) > 0 {
+			var oids []string
+			for _, oid := range cert.UnhandledCriticalExtensions {
+				oids = append(oids, oid.String())
+			}
+			fmt.Fprintf(out, "⚠️  Certificate #%d (%s) has unhandled critical extensions: %s\n\n", certCount, cert.Subject.String(), strings.Join(oids, ", "))
+			findings = append(findings, finding{ruleID: "unhandled-critical-extension", certIndex: certCount, line: line,
+				message: fmt.Sprintf("Certificate %s carries critical extensions that were not processed: %s", cert.Subject.String(), strings.Join(oids, ", "))})
+		}
+
+		// Check if this is ISRG Root X1
+		if isISRGRootX1(cert) {
+			fmt.Fprintf(out, "✅ Found ISRG Root X1 (Certificate #%d)\n", certCount)
+			fmt.Fprintf(out, "   Subject: %s\n", cert.Subject.String())
+			fmt.Fprintf(out, "   Issuer:  %s\n", cert.Issuer.String())
+			
+			// Check if it's self-signed (root certificate)
+			if cert.Subject.String() == cert.Issuer.String() {
+				fmt.Fprintf(out, "   ✅ Self-signed: YES (this is a ROOT certificate)\n")
+			} else {
+				fmt.Fprintf(out, "   ⚠️  Self-signed: NO (not a root)\n")
+			}
+			fmt.Fprintln(out)
+		}
+		
+		// Check if this is a Let's Encrypt intermediate (R3, R10, R11, R12, R13, E1, E2, etc.)
+		if isLetsEncryptIntermediate(cert) {
+			fmt.Fprintf(out, "✅ Found Let's Encrypt Intermediate %s (Certificate #%d)\n", cert.Subject.CommonName, certCount)
+			fmt.Fprintf(out, "   Subject: %s\n", cert.Subject.String())
+			fmt.Fprintf(out, "   Issuer:  %s\n", cert.Issuer.String())
+			fmt.Fprintf(out, "   ✅ Signed by: ISRG Root X1\n")
+			if now.After(cert.NotAfter) {
+				fmt.Fprintf(out, "   ❌ EXPIRED: %s\n", cert.NotAfter.Format("2006-01-02"))
+				findings = append(findings, finding{ruleID: "expired-intermediate", certIndex: certCount, line: line,
+					message: fmt.Sprintf("Let's Encrypt intermediate %s expired on %s; chains through it fail validation even though it is present", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))})
+			}
+			fmt.Fprintln(out)
+		}
+	}
+
+	if encoding == "DER" {
+		// x509.ParseCertificates accepts one or more concatenated DER
+		// certificates; leave nothing for the PEM loop below
+		rest = nil
+		derCerts, err := x509.ParseCertificates(caData)
+		if err != nil {
+			fmt.Fprintf(out, "Error parsing certificate: %v\n", err)
+			parseErrors++
+			findings = append(findings, finding{ruleID: "parse-error",
+				message: fmt.Sprintf("Input is not PEM and could not be parsed as DER: %v", err)})
+		}
+		for _, cert := range derCerts {
+			analyze(cert, 0)
+		}
+	}
+
+	// Parse all certificates
+	for {
+		var block *pem.Block
+		prev := rest
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		// Line of the BEGIN marker, for locating findings in the file
+		line := lineAt(caData, len(caData)-len(prev)+bytes.Index(prev, []byte("-----BEGIN")))
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			fmt.Fprintf(out, "Error parsing certificate: %v\n", err)
+			parseErrors++
+			findings = append(findings, finding{ruleID: "parse-error", line: line,
+				message: fmt.Sprintf("Certificate could not be parsed: %v", err)})
+			continue
+		}
+
+		analyze(cert, line)
+	}
+
+	// pem.Decode also stops at content it cannot decode, such as a
+	// truncated final certificate, so make sure nothing was left behind
+	if trimmed := bytes.TrimLeft(rest, " \t\r\n"); len(bytes.TrimSpace(trimmed)) > 0 {
+		offset := len(caData) - len(trimmed)
+		fmt.Fprintf(out, "⚠️  Trailing %d bytes at offset %d could not be decoded as PEM (truncated or corrupted bundle?)\n\n", len(trimmed), offset)
+		findings = append(findings, finding{ruleID: "trailing-data", line: lineAt(caData, offset),
+			message: fmt.Sprintf("Trailing %d bytes at offset %d could not be decoded as PEM", len(trimmed), offset)})
+	}
+
+	fmt.Fprintf(out, "Total certificates in bundle: %d\n\n", certCount)
+
+	findings = append(findings, checkRootPurposes(certs)...)
+	findings = append(findings, checkCrossSigns(certs, now)...)
+	findings = append(findings, checkIssuersPresent(certs)...)
+	findings = append(findings, checkBasicConstraints(certs)...)
+	selfSignedLeaves := checkSelfSignedLeaves(certs)
+	findings = append(findings, selfSignedLeaves...)
+	chainFindings, verifiedChains := checkChainVerification(certs, now, trustStore)
+	findings = append(findings, chainFindings...)
+	if *checkOCSP {
+		findings = append(findings, checkRevocation(certs)...)
+	}
+	if *crlFile != "" {
+		crlFindings, err := checkCRL(certs, *crlFile, now)
+		if err != nil {
+			fmt.Printf("Error reading CRL: %v\n", err)
+			os.Exit(1)
+		}
+		findings = append(findings, crlFindings...)
+	}
+	
+	rootFindings, rootMissing := checkExpectedRoots(certs, expectedRoots, trustStore)
+	findings = append(findings, rootFindings...)
+
+	// The Let's Encrypt walkthrough below is a secondary, name-based
+	// diagnostic; the key ID match above is what decides the exit code
+	letsEncryptExpected := false
+	for _, name := range expectedRoots {
+		if name == "ISRG Root X1" {
+			letsEncryptExpected = true
+		}
+	}
+	le := analyzeLetsEncrypt(certs, now)
+	for i, cert := range certs {
+		for _, mismatch := range le.keyIDMismatches {
+			if cert == mismatch {
+				findings = append(findings, finding{ruleID: "key-id-mismatch", certIndex: i + 1,
+					message: fmt.Sprintf("Let's Encrypt intermediate %s names ISRG Root X1 as issuer, but its AuthorityKeyId %X matches no ISRG Root X1 SubjectKeyId in the bundle", cert.Subject.CommonName, cert.AuthorityKeyId)})
+			}
+		}
+	}
+	if letsEncryptExpected {
+		printLetsEncryptAnalysis(le)
+	}
+
+	snapshot := publicRootSnapshot
+	source := "embedded snapshot"
+	if *publicRootsFile != "" {
+		snapshot, err = loadPublicRoots(*publicRootsFile)
+		if err != nil {
+			fmt.Printf("Error reading public roots file: %v\n", err)
+			os.Exit(1)
+		}
+		source = *publicRootsFile
+	}
+	if *publicRoots {
+		findings = append(findings, checkPublicRoots(buildChainGraph(certs), snapshot, source, now)...)
+	}
+
+	findings = append(findings, runValidators(enabled, certs)...)
+
+	properties := map[string]interface{}{"cryptoBackend": cryptoBackend()}
+	if *score {
+		trusted := append([]publicRoot{}, snapshot...)
+		if *internalRoots != "" {
+			internal, err := loadPublicRoots(*internalRoots)
+			if err != nil {
+				fmt.Printf("Error reading internal roots file: %v\n", err)
+				os.Exit(1)
+			}
+			trusted = append(trusted, internal...)
+		}
+		properties["trustScore"] = trustScore(buildChainGraph(certs), findings, trusted)
+	}
+
+	if *output == "mermaid" {
+		writeMermaid(os.Stdout, buildChainGraph(certs))
+	}
+
+	if *output == "tree" {
+		writeTree(os.Stdout, buildChainGraph(certs))
+	}
+
+	if *output == "sarif" {
+		if err := writeSARIF(os.Stdout, caFile, findings, enabled, properties); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing SARIF: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *outputChain != "" {
+		if err := writeChainFile(*outputChain, verifiedChains, len(chainFindings) == 0); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing -output-chain: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "✅ Verified chain written to %s\n", *outputChain)
+	}
+
+	if code := exitCode(findings, parseErrors, rootMissing); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// Exit codes, documented in the usage output.
+const (
+	exitMissingRoot         = 1
+	exitParseError          = 2
+	exitExpiredIntermediate = 3
+	exitChainUnverified     = 4
+	exitPathLength          = 5
+	exitErrorFinding        = 6
+)
+
+// exitCode maps the analysis to an exit code. An unparseable bundle is a
+// worse problem than an incomplete chain, and may be hiding the missing
+// root, so it takes precedence; the specific chain problems come next, and
+// any other error-level finding, such as a revocation, fails the run last.
+func exitCode(findings []finding, parseErrors int, rootMissing bool) int {
+	if parseErrors > 0 {
+		return exitParseError
+	}
+	if rootMissing {
+		return exitMissingRoot
+	}
+	for _, rule := range []struct {
+		id   string
+		code int
+	}{
+		{"expired-intermediate", exitExpiredIntermediate},
+		{"chain-unverified", exitChainUnverified},
+		{"path-length-exceeded", exitPathLength},
+	} {
+		for _, f := range findings {
+			if f.ruleID == rule.id {
+				return rule.code
+			}
+		}
+	}
+	for _, f := range findings {
+		if findingLevel(f.ruleID) == "error" {
+			return exitErrorFinding
+		}
+	}
+	return 0
+}
+
+func checkMark(present bool) string {
+	if present {
+		return "✅ PRESENT"
+	}
+	return "❌ MISSING"
+}
+
+// isISRGRootX1 reports whether a certificate claims to be ISRG Root X1.
+// Like the rest of the Let's Encrypt walkthrough this goes by name only.
+func isISRGRootX1(cert *x509.Certificate) bool {
+	return cert.Subject.CommonName == "ISRG Root X1"
+}
+
+// isLetsEncryptIntermediate reports whether a certificate is a Let's
+// Encrypt intermediate (R3, R10, R11, R12, R13, E1, E2, etc.) issued by
+// ISRG Root X1.
+func isLetsEncryptIntermediate(cert *x509.Certificate) bool {
+	return len(cert.Subject.Organization) > 0 && cert.Subject.Organization[0] == "Let's Encrypt" &&
+		cert.Issuer.CommonName == "ISRG Root X1"
+}
+
+// letsEncryptAnalysis is the verdict of the Let's Encrypt walkthrough,
+// kept separate from its formatting so the verdicts can be tested.
+type letsEncryptAnalysis struct {
+	foundRoot            bool
+	foundIntermediate    bool
+	intermediate         *x509.Certificate // the last Let's Encrypt intermediate in the bundle
+	expiredIntermediates []*x509.Certificate
+	rootKeyIDs           [][]byte            // SubjectKeyIds of the ISRG Root X1 roots
+	keyIDMismatches      []*x509.Certificate // intermediates whose AuthorityKeyId matches none of rootKeyIDs
+	rootCopies           int
+	selfSignedLeavesOnly bool
+	chainComplete        bool
+	problems             []string
+}
+
+// analyzeLetsEncrypt works out whether a bundle carries the Let's Encrypt
+// chain: an intermediate signed by ISRG Root X1 and the self-signed root.
+func analyzeLetsEncrypt(certs []*x509.Certificate, now time.Time) letsEncryptAnalysis {
+	var le letsEncryptAnalysis
+	for _, cert := range certs {
+		if isISRGRootX1(cert) && cert.Subject.String() == cert.Issuer.String() {
+			le.foundRoot = true
+			le.rootCopies++
+			if len(cert.SubjectKeyId) > 0 {
+				le.rootKeyIDs = append(le.rootKeyIDs, cert.SubjectKeyId)
+			}
+		}
+		if isLetsEncryptIntermediate(cert) {
+			le.foundIntermediate = true
+			le.intermediate = cert
+			if now.After(cert.NotAfter) {
+				le.expiredIntermediates = append(le.expiredIntermediates, cert)
+			}
+		}
+	}
+	// The names above say which root issued each intermediate; the key IDs
+	// say which key did. A root rolled over or cross-signed under the same
+	// name passes the first test and fails the second.
+	if len(le.rootKeyIDs) > 0 {
+		for _, cert := range certs {
+			if !isLetsEncryptIntermediate(cert) || len(cert.AuthorityKeyId) == 0 {
+				continue
+			}
+			linked := false
+			for _, keyID := range le.rootKeyIDs {
+				if bytes.Equal(cert.AuthorityKeyId, keyID) {
+					linked = true
+				}
+			}
+			if !linked {
+				le.keyIDMismatches = append(le.keyIDMismatches, cert)
+			}
+		}
+	}
+
+	g := buildChainGraph(certs)
+	le.selfSignedLeavesOnly = len(certs) > 0
+	for i := range certs {
+		if !isSelfSignedLeaf(g, i) {
+			le.selfSignedLeavesOnly = false
+		}
+	}
+	le.chainComplete = le.foundRoot && le.foundIntermediate && len(le.expiredIntermediates) == 0 && len(le.keyIDMismatches) == 0
+
+	if le.foundIntermediate && !le.foundRoot {
+		le.problems = append(le.problems, "Let's Encrypt intermediate is present but ISRG Root X1 is not")
+	}
+	for _, cert := range le.keyIDMismatches {
+		le.problems = append(le.problems, fmt.Sprintf("Let's Encrypt intermediate %s names ISRG Root X1 as issuer but its AuthorityKeyId %X matches no ISRG Root X1 in the bundle", cert.Subject.CommonName, cert.AuthorityKeyId))
+	}
+	for _, cert := range le.expiredIntermediates {
+		le.problems = append(le.problems, fmt.Sprintf("Let's Encrypt intermediate %s expired on %s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+	}
+	if le.rootCopies > 1 {
+		le.problems = append(le.problems, fmt.Sprintf("ISRG Root X1 appears %d times", le.rootCopies))
+	}
+	return le
+}
+
+// printLetsEncryptAnalysis writes the Trust Chain Analysis section.
+func printLetsEncryptAnalysis(le letsEncryptAnalysis) {
+	fmt.Fprintf(out, "=== Trust Chain Analysis ===\n\n")
+
+	if le.foundIntermediate && !le.foundRoot {
+		fmt.Fprintln(out, "❌ PROBLEM DETECTED:")
+		fmt.Fprintln(out, "   • Let's Encrypt intermediate certificate IS present")
+		fmt.Fprintln(out, "   • Let's Encrypt intermediate is signed by ISRG Root X1")
+		fmt.Fprintln(out, "   • ISRG Root X1 root certificate is NOT present")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "This means:")
+		fmt.Fprintln(out, "   • The bundle references ISRG Root X1 as an issuer")
+		fmt.Fprintln(out, "   • But the actual ISRG Root X1 root CA is missing")
+		fmt.Fprintln(out, "   • TLS validation will FAIL for certs signed by Let's Encrypt")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Solution: Use --use-system-trust-store=true to include")
+		fmt.Fprintln(out, "          ISRG Root X1 from the system trust store")
+
+	} else if len(le.keyIDMismatches) > 0 {
+		fmt.Fprintln(out, "❌ PROBLEM DETECTED: KEY ID MISMATCH")
+		for _, cert := range le.keyIDMismatches {
+			fmt.Fprintf(out, "   • Let's Encrypt intermediate %s names ISRG Root X1 as its issuer\n", cert.Subject.CommonName)
+			fmt.Fprintf(out, "     but was issued by the key with SubjectKeyId %X\n", cert.AuthorityKeyId)
+		}
+		for _, keyID := range le.rootKeyIDs {
+			fmt.Fprintf(out, "   • ISRG Root X1 in the bundle has SubjectKeyId %X\n", keyID)
+		}
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "This means:")
+		fmt.Fprintln(out, "   • The names match but the keys do not: the root in the bundle did not issue the intermediate")
+		fmt.Fprintln(out, "   • This is the hallmark of a root rollover or cross-sign that reused the name")
+		fmt.Fprintln(out, "   • TLS validation will FAIL unless the root whose key issued the intermediate is trusted")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Solution: Add the ISRG Root X1 whose SubjectKeyId matches the intermediate's")
+		fmt.Fprintln(out, "          AuthorityKeyId, or use the intermediate issued by the root in the bundle")
+
+	} else if le.foundIntermediate && le.foundRoot && len(le.expiredIntermediates) > 0 {
+		// Presence is not enough: a stale intermediate left behind
+		// after Let's Encrypt rotated it still "completes" the chain
+		fmt.Fprintln(out, "❌ PROBLEM DETECTED: EXPIRED INTERMEDIATE")
+		for _, cert := range le.expiredIntermediates {
+			fmt.Fprintf(out, "   • Let's Encrypt intermediate %s IS present but expired on %s\n", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+		}
+		fmt.Fprintln(out, "   • ISRG Root X1 root certificate IS present")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "This means:")
+		fmt.Fprintln(out, "   • The chain looks complete, but validation checks every certificate's dates")
+		fmt.Fprintln(out, "   • Any certificate served with the expired intermediate will FAIL validation")
+		fmt.Fprintln(out, "     (\"x509: certificate has expired or is not yet valid\")")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Solution: Rotate the bundle to the current Let's Encrypt intermediates, and make")
+		fmt.Fprintln(out, "          sure the server serves its renewed chain rather than a cached one")
+
+	} else if le.chainComplete {
+		fmt.Fprintln(out, "✅ TRUST CHAIN COMPLETE:")
+		fmt.Fprintln(out, "   • R13 intermediate certificate IS present")
+		fmt.Fprintln(out, "   • ISRG Root X1 root certificate IS present")
+		fmt.Fprintln(out, "   • TLS validation should work for Let's Encrypt certificates")
+
+	} else if le.selfSignedLeavesOnly {
+		fmt.Fprintln(out, "⚠️  NOT A CA BUNDLE:")
+		fmt.Fprintln(out, "   • The bundle holds only self-signed leaf certificates, not CAs")
+		fmt.Fprintln(out, "   • There is no chain to any root, Let's Encrypt or otherwise")
+		fmt.Fprintln(out, "   • Clients will only accept the server if they trust that exact certificate")
+
+	} else if !le.foundIntermediate && !le.foundRoot {
+		fmt.Fprintln(out, "ℹ️  NO LET'S ENCRYPT CERTIFICATES:")
+		fmt.Fprintln(out, "   • Neither R13 nor ISRG Root X1 found")
+		fmt.Fprintln(out, "   • This bundle uses different CAs (likely internal only)")
+		fmt.Fprintln(out, "   • For managed clusters with Let's Encrypt OAuth routes,")
+		fmt.Fprintln(out, "     use --use-system-trust-store=true")
+	}
+	if le.rootCopies > 1 {
+		// Harmless to validation, but usually a sign that bundles were
+		// concatenated without deduplicating
+		fmt.Fprintf(out, "⚠️  ISRG Root X1 appears %d times in the bundle\n", le.rootCopies)
+		fmt.Fprintln(out, "   → Deduplicate the bundle so the next root rotation only has one copy to replace")
+	}
+	fmt.Fprintln(out)
+
+	// Show what's actually needed for validation
+	if le.intermediate != nil {
+		fmt.Fprintln(out, "=== To Validate an OAuth Cert Signed by R13 ===")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Certificate chain needed:")
+		fmt.Fprintln(out, "  1. OAuth Server Cert (e.g., *.example.com)")
+		fmt.Fprintln(out, "     └─ signed by: R13")
+		fmt.Fprintf(out, "  2. R13 Intermediate (%s in bundle)\n", checkMark(le.foundIntermediate))
+		fmt.Fprintln(out, "     └─ signed by: ISRG Root X1")
+		fmt.Fprintf(out, "  3. ISRG Root X1 Root (%s in bundle)\n", checkMark(le.foundRoot))
+		fmt.Fprintln(out, "     └─ self-signed (root)")
+		fmt.Fprintln(out)
+
+		if !le.foundRoot {
+			fmt.Fprintln(out, "❌ Chain is INCOMPLETE - missing step 3!")
+		} else if len(le.keyIDMismatches) > 0 {
+			fmt.Fprintln(out, "❌ Chain is BROKEN - step 2 was not issued by the step 3 in the bundle!")
+		} else if !le.chainComplete {
+			fmt.Fprintln(out, "❌ Chain is complete but step 2 has EXPIRED!")
+		} else {
+			fmt.Fprintln(out, "✅ Chain is COMPLETE")
+		}
+	}
+}
+
+// chainGraph links each certificate in a bundle to its issuer, when the
+// issuer is also in the bundle.
+type chainGraph struct {
+	certs  []*x509.Certificate
+	parent []int // index of the issuing cert, -1 for roots and orphans
+}
+
+// isSelfSigned reports whether a certificate is its own issuer.
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	return len(cert.AuthorityKeyId) == 0 || bytes.Equal(cert.AuthorityKeyId, cert.SubjectKeyId)
+}
+
+// buildChainGraph finds each certificate's issuer by AuthorityKeyId ->
+// SubjectKeyId, falling back to the issuer/subject DN when key IDs are absent.
+func buildChainGraph(certs []*x509.Certificate) *chainGraph {
+	g := &chainGraph{certs: certs, parent: make([]int, len(certs))}
+	for i, cert := range certs {
+		g.parent[i] = -1
+		if isSelfSigned(cert) {
+			continue
+		}
+		for j, candidate := range certs {
+			if i == j {
+				continue
+			}
+			if len(cert.AuthorityKeyId) > 0 && len(candidate.SubjectKeyId) > 0 {
+				if bytes.Equal(cert.AuthorityKeyId, candidate.SubjectKeyId) {
+					g.parent[i] = j
+					break
+				}
+				continue
+			}
+			if bytes.Equal(cert.RawIssuer, candidate.RawSubject) {
+				g.parent[i] = j
+				break
+			}
+		}
+	}
+	return g
+}
+
+// isRoot reports whether cert i is a self-signed root.
+func (g *chainGraph) isRoot(i int) bool {
+	return isSelfSigned(g.certs[i])
+}
+
+// isLeaf reports whether cert i issued nothing else in the bundle and is
+// not a CA.
+func (g *chainGraph) isLeaf(i int) bool {
+	if g.certs[i].IsCA {
+		return false
+	}
+	for _, p := range g.parent {
+		if p == i {
+			return false
+		}
+	}
+	return true
+}
+
+// certLabel is a short display name for a certificate.
+func certLabel(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// writeMermaid renders the chain graph as a Mermaid flowchart with edges
+// from issuer to subject. Roots, leaves, and issuers missing from the bundle
+// are styled distinctly.
+func writeMermaid(w io.Writer, g *chainGraph) {
+	quote := func(s string) string {
+		return strings.ReplaceAll(s, "\"", "#quot;")
+	}
+
+	fmt.Fprintln(w, "graph TD")
+	fmt.Fprintf(w, "    %%%% crypto backend: %s\n", cryptoBackend())
+	for i, cert := range g.certs {
+		class := ""
+		switch {
+		case g.isRoot(i):
+			class = ":::root"
+		case g.isLeaf(i):
+			class = ":::leaf"
+		}
+		fmt.Fprintf(w, "    c%d[\"%s\"]%s\n", i, quote(certLabel(cert)), class)
+	}
+
+	missing := 0
+	for i, cert := range g.certs {
+		switch {
+		case g.parent[i] >= 0:
+			fmt.Fprintf(w, "    c%d --> c%d\n", g.parent[i], i)
+		case !g.isRoot(i):
+			// Issuer not in the bundle: show it as a dangling node
+			fmt.Fprintf(w, "    m%d[\"missing: %s\"]:::missing -.-> c%d\n", missing, quote(cert.Issuer.String()), i)
+			missing++
+		}
+	}
+
+	fmt.Fprintln(w, "    classDef root fill:#d4edda,stroke:#28a745")
+	fmt.Fprintln(w, "    classDef leaf fill:#cce5ff,stroke:#004085")
+	fmt.Fprintln(w, "    classDef missing fill:#f8d7da,stroke:#dc3545,stroke-dasharray: 5 5")
+}
+
+// writeTree renders the chain graph as an indented tree: each root with the
+// certificates it anchors nested beneath it, then the orphans, whose issuer
+// is not in the bundle, each with its own subtree.
+func writeTree(w io.Writer, g *chainGraph) {
+	children := make([][]int, len(g.certs))
+	for i, p := range g.parent {
+		if p >= 0 {
+			children[p] = append(children[p], i)
+		}
+	}
+
+	label := func(i int) string {
+		kind := "intermediate"
+		switch {
+		case g.isRoot(i):
+			kind = "root"
+		case g.isLeaf(i):
+			kind = "leaf"
+		}
+		return fmt.Sprintf("%s (#%d, %s)", certLabel(g.certs[i]), i+1, kind)
+	}
+
+	// Certificates that issued each other with no root above them would
+	// otherwise loop forever, so each is printed once
+	printed := make([]bool, len(g.certs))
+	var walk func(i int, prefix string)
+	walk = func(i int, prefix string) {
+		printed[i] = true
+		for n, child := range children[i] {
+			if printed[child] {
+				continue
+			}
+			branch, indent := "├── ", "│   "
+			if n == len(children[i])-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, label(child))
+			walk(child, prefix+indent)
+		}
+	}
+
+	fmt.Fprintln(w, "=== Roots ===")
+	roots := 0
+	for i := range g.certs {
+		if g.isRoot(i) {
+			fmt.Fprintln(w, label(i))
+			walk(i, "")
+			roots++
+		}
+	}
+	if roots == 0 {
+		fmt.Fprintln(w, "(none)")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=== Orphans ===")
+	orphans := 0
+	for i, cert := range g.certs {
+		if printed[i] || (g.parent[i] >= 0 && !isIssuerLoop(g, i)) {
+			continue
+		}
+		if g.parent[i] >= 0 {
+			fmt.Fprintf(w, "%s, in an issuer loop with no root\n", label(i))
+		} else {
+			fmt.Fprintf(w, "%s, issuer not in bundle: %s\n", label(i), cert.Issuer.String())
+		}
+		walk(i, "")
+		orphans++
+	}
+	if orphans == 0 {
+		fmt.Fprintln(w, "(none)")
+	}
+}
+
+// isIssuerLoop reports whether following cert i's issuers leads back to i.
+func isIssuerLoop(g *chainGraph, i int) bool {
+	for p, steps := g.parent[i], 0; p >= 0 && steps < len(g.certs); p, steps = g.parent[p], steps+1 {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "ServerAuth",
+	x509.ExtKeyUsageClientAuth:      "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:     "CodeSigning",
+	x509.ExtKeyUsageEmailProtection: "EmailProtection",
+	x509.ExtKeyUsageTimeStamping:    "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+// rootPurpose describes what a root may anchor, based on its EKUs. No EKU
+// means the root is unconstrained.
+func rootPurpose(cert *x509.Certificate) (string, bool) {
+	if len(cert.ExtKeyUsage) == 0 {
+		return "any purpose (no EKU)", true
+	}
+	var names []string
+	serverAuth := false
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageServerAuth || eku == x509.ExtKeyUsageAny {
+			serverAuth = true
+		}
+		if name, ok := extKeyUsageNames[eku]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("Unknown(%d)", eku))
+		}
+	}
+	return strings.Join(names, ", "), serverAuth
+}
+
+// checkRootPurposes reports, for every self-signed root in the bundle,
+// whether it can anchor TLS server certificates: it must be a CA, be allowed
+// to sign certificates, and not be restricted by EKU to other purposes such
+// as code signing or email.
+func checkRootPurposes(certs []*x509.Certificate) []finding {
+	var findings []finding
+	header := false
+	for i, cert := range certs {
+		if !isSelfSigned(cert) {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(out, "=== Root Purpose ===\n\n")
+			header = true
+		}
+
+		purpose, serverAuth := rootPurpose(cert)
+		var problems []string
+		if !cert.BasicConstraintsValid || !cert.IsCA {
+			problems = append(problems, "not marked as a CA (basicConstraints)")
+		}
+		if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+			problems = append(problems, "keyUsage does not allow certificate signing")
+		}
+		if !serverAuth {
+			problems = append(problems, "EKU does not include ServerAuth")
+		}
+
+		fmt.Fprintf(out, "Root %s (Certificate #%d)\n", cert.Subject.String(), i+1)
+		fmt.Fprintf(out, "   Purpose: %s\n", purpose)
+		if len(problems) == 0 {
+			fmt.Fprintf(out, "   ✅ Valid TLS server-auth trust anchor\n\n")
+			continue
+		}
+		for _, p := range problems {
+			fmt.Fprintf(out, "   ⚠️  %s\n", p)
+		}
+		fmt.Fprintln(out, "   → Server certificates chaining to this root will fail validation")
+		fmt.Fprintln(out)
+		findings = append(findings, finding{ruleID: "root-purpose", certIndex: i + 1,
+			message: fmt.Sprintf("Root %s cannot anchor TLS server certs: %s", cert.Subject.String(), strings.Join(problems, "; "))})
+	}
+	return findings
+}
+
+// readBundle reads a CA bundle from path, or from stdin when path is "-",
+// so the tool can sit at the end of a pipe such as "oc extract ... --to=-".
+func readBundle(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// fetchServerChain connects to addr and returns the certificates the
+// server presents as a PEM bundle, so the live chain goes through the same
+// analysis as a file. Verification is skipped on purpose: the point is to
+// inspect chains that may not verify. That skips the hostname check too, so
+// the leaf is checked against serverName here and a mismatch returned as a
+// finding.
+func fetchServerChain(target, serverName string, timeout time.Duration) ([]byte, []finding, error) {
+	addr, host, err := connectAddress(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if serverName == "" {
+		serverName = host
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	// Go sends no SNI for an IP address, whatever ServerName says
+	sni := serverName
+	if net.ParseIP(serverName) != nil {
+		sni = "none (IP address)"
+	}
+	peers := conn.ConnectionState().PeerCertificates
+	fmt.Fprintf(out, "Connected to %s (dialed %s, SNI: %s), server presented %d certificates\n", addr, conn.RemoteAddr(), sni, len(peers))
+	fmt.Fprintln(out, "ℹ️  Servers normally omit their root; one left out is looked up in the trust store instead")
+
+	var findings []finding
+	if len(peers) > 0 {
+		if err := peers[0].VerifyHostname(serverName); err != nil {
+			fmt.Fprintf(out, "❌ Hostname mismatch: %v\n", err)
+			findings = append(findings, finding{ruleID: "hostname-mismatch", certIndex: 1,
+				message: fmt.Sprintf("Server certificate %s is not valid for %s: %v", peers[0].Subject.String(), serverName, err)})
+		} else {
+			fmt.Fprintf(out, "✅ Hostname: %s is covered by the server certificate\n", serverName)
+		}
+	}
+	fmt.Fprintln(out)
+
+	var bundle bytes.Buffer
+	for _, cert := range peers {
+		pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return bundle.Bytes(), findings, nil
+}
+
+// connectTrustStore returns the roots a -connect chain is verified
+// against: the certificates in bundleFile when one is given, otherwise the
+// system store.
+func connectTrustStore(bundleFile string) (*x509.CertPool, error) {
+	if bundleFile == "" {
+		return x509.SystemCertPool()
+	}
+	data, err := readBundle(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCerts(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", bundleFile, err)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// connectAddress turns a -connect target into the address to dial and the
+// host to send as SNI. The port defaults to 443, and IPv6 literals may be
+// bracketed, with or without a port ([::1]:8443, [::1]), or bare (::1).
+func connectAddress(target string) (addr, host string, err error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		// No port: a hostname, an IPv4 address, or an IPv6 literal
+		host, port = strings.TrimSuffix(strings.TrimPrefix(target, "["), "]"), "443"
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", "", fmt.Errorf("expected host, host:port or [ipv6]:port, got %q", target)
+		}
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("no host in %q", target)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", "", fmt.Errorf("invalid port %q in %q", port, target)
+	}
+	return net.JoinHostPort(host, port), host, nil
+}
+
+// lineAt returns the 1-based line number of a byte offset in data.
+func lineAt(data []byte, offset int) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// finding is a single verification problem, reported in the text output
+// as it is found and collected for machine-readable formats.
+type finding struct {
+	ruleID    string
+	message   string
+	certIndex int // 1-based position in the bundle, 0 if not cert-specific
+	line      int // line in the bundle file, 0 if unknown
+}
+
+// findingLevel returns the SARIF level of a rule: one of sarifRules, or a
+// custom validator's name.
+func findingLevel(ruleID string) string {
+	for _, r := range sarifRules {
+		if r.id == ruleID {
+			return r.level
+		}
+	}
+	for _, rv := range validators {
+		if rv.name == ruleID {
+			return rv.level
+		}
+	}
+	return ""
+}
+
+// sarifRules describes every rule ID a finding can carry.
+var sarifRules = []struct {
+	id, level, description string
+}{
+	{"missing-root", "error", "Intermediate present but its root certificate is missing from the bundle"},
+	{"expired-cert", "error", "Certificate is past its NotAfter date"},
+	{"expired-intermediate", "error", "Let's Encrypt intermediate is present but expired, so chains through it fail validation"},
+	{"key-id-mismatch", "warning", "Let's Encrypt intermediate names ISRG Root X1 as issuer but its AuthorityKeyId matches no ISRG Root X1 in the bundle"},
+	{"weak-signature", "warning", "Certificate is signed with a weak algorithm (MD5 or SHA-1)"},
+	{"parse-error", "error", "PEM block could not be parsed as an X.509 certificate"},
+	{"trailing-data", "warning", "Bundle contains trailing data that is not valid PEM"},
+	{"root-purpose", "warning", "Root certificate is not usable as a TLS server-auth trust anchor"},
+	{"unhandled-critical-extension", "error", "Certificate carries a critical extension the verifier does not understand and will be rejected"},
+	{"cross-sign-eol", "warning", "Chain depends on a cross-signed root certificate that has an end-of-life date"},
+	{"chain-unverified", "error", "Certificate does not verify to a root in the bundle"},
+	{"missing-issuer", "warning", "Certificate's issuer is not in the bundle, so the chain has a gap"},
+	{"issuer-not-ca", "error", "Certificate issues another in the bundle but is not a valid CA (basic constraints missing or CA:FALSE)"},
+	{"root-not-ca", "warning", "Self-signed certificate is not marked as a CA, so it can only be trusted directly and cannot issue others"},
+	{"self-signed-leaf", "warning", "Self-signed certificate is a leaf, not a CA, so no real chain exists and clients must trust it directly"},
+	{"path-length-exceeded", "error", "Chain has more intermediates below a CA than its path length constraint allows"},
+	{"ocsp-revoked", "error", "OCSP responder reports the certificate as revoked"},
+	{"ocsp-unknown", "warning", "OCSP responder does not know the certificate"},
+	{"ocsp-unavailable", "warning", "OCSP status could not be determined because the responder could not be queried or its response was invalid"},
+	{"crl-revoked", "error", "Certificate is listed as revoked in the CRL"},
+	{"crl-signature-invalid", "error", "CRL signature does not verify against its issuer in the bundle, so its entries were not trusted"},
+	{"crl-issuer-missing", "warning", "CRL issuer is not in the bundle, so the CRL's signature could not be checked"},
+	{"crl-expired", "warning", "CRL is past its NextUpdate date and may be missing recent revocations"},
+	{"hostname-mismatch", "error", "Server certificate does not cover the name -connect reached it by"},
+	{"not-publicly-trusted", "note", "Chain does not end at a root in the public roots snapshot"},
+}
+
+// writeSARIF renders findings as a SARIF 2.1.0 log so they can be ingested
+// by code-scanning dashboards. Enabled validators are listed as rules after
+// the built-in sarifRules.
+func writeSARIF(w io.Writer, bundleFile string, findings []finding, enabled []registeredValidator, properties map[string]interface{}) error {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID                   string  `json:"id"`
+		ShortDescription     message `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	type region struct {
+		StartLine int `json:"startLine"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *region `json:"region,omitempty"`
+	}
+	type logicalLocation struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation  `json:"physicalLocation"`
+		LogicalLocations []logicalLocation `json:"logicalLocations,omitempty"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	var rules []rule
+	for _, r := range sarifRules {
+		sr := rule{ID: r.id, ShortDescription: message{r.description}}
+		sr.DefaultConfiguration.Level = r.level
+		rules = append(rules, sr)
+	}
+	for _, rv := range enabled {
+		sr := rule{ID: rv.name, ShortDescription: message{rv.description}}
+		sr.DefaultConfiguration.Level = rv.level
+		rules = append(rules, sr)
+	}
+
+	results := []result{}
+	for _, f := range findings {
+		loc := location{}
+		loc.PhysicalLocation.ArtifactLocation.URI = bundleFile
+		if f.line > 0 {
+			loc.PhysicalLocation.Region = &region{StartLine: f.line}
+		}
+		if f.certIndex > 0 {
+			loc.LogicalLocations = []logicalLocation{{Name: fmt.Sprintf("certificate[%d]", f.certIndex), Kind: "element"}}
+		}
+		results = append(results, result{
+			RuleID:    f.ruleID,
+			Level:     findingLevel(f.ruleID),
+			Message:   message{f.message},
+			Locations: []location{loc},
+		})
+	}
+
+	sarifLog := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":  "verify_root_ca",
+						"rules": rules,
+					},
+				},
+				"properties": properties,
+				"results": results,
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog)
+}
+
+// validator is a site-specific policy check run after the standard
+// analysis. Validate receives every certificate in the bundle in file order,
+// not a verified chain, so checks must not assume issuer/subject ordering.
+// Findings without a ruleID are attributed to the validator.
+type validator interface {
+	Validate(bundle []*x509.Certificate) []finding
+}
+
+// validatorFunc adapts a plain function to the validator interface.
+type validatorFunc func(bundle []*x509.Certificate) []finding
+
+func (f validatorFunc) Validate(bundle []*x509.Certificate) []finding { return f(bundle) }
+
+// registeredValidator is a validator selectable by name with -validators.
+// Organization-specific rules are added by appending to validators.
+type registeredValidator struct {
+	name, level, description string
+	v                        validator
+}
+
+var validators = []registeredValidator{
+	{"min-rsa-2048", "error", "RSA keys must be at least 2048 bits", validatorFunc(validateMinRSA2048)},
+	{"leaf-max-398d", "warning", "Leaf certificates must not be valid for more than 398 days", validatorFunc(validateLeafValidity)},
+	{"ca-has-ski", "warning", "CA certificates must carry a Subject Key Identifier", validatorFunc(validateCASubjectKeyID)},
+}
+
+func listValidators() {
+	fmt.Println("Available validators:")
+	for _, rv := range validators {
+		fmt.Printf("  %-14s %s\n", rv.name, rv.description)
+	}
+}
+
+// lookupValidators resolves a comma-separated list of validator names.
+// A name given more than once is enabled once.
+func lookupValidators(names string) ([]registeredValidator, error) {
+	var enabled []registeredValidator
+	seen := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		found := false
+		for _, rv := range validators {
+			if rv.name == name {
+				enabled = append(enabled, rv)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown validator %q (use -validators list to see the available ones)", name)
+		}
+	}
+	return enabled, nil
+}
+
+// runValidators runs each enabled validator over the bundle and reports
+// their findings in the text output.
+func runValidators(enabled []registeredValidator, certs []*x509.Certificate) []finding {
+	if len(enabled) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "=== Custom Validators ===")
+	fmt.Fprintln(out)
+	var all []finding
+	for _, rv := range enabled {
+		results := rv.v.Validate(certs)
+		if len(results) == 0 {
+			fmt.Fprintf(out, "✅ %s\n", rv.name)
+			continue
+		}
+		fmt.Fprintf(out, "❌ %s\n", rv.name)
+		for _, f := range results {
+			if f.ruleID == "" {
+				f.ruleID = rv.name
+			}
+			fmt.Fprintf(out, "   • %s\n", f.message)
+			all = append(all, f)
+		}
+	}
+	fmt.Fprintln(out)
+	return all
+}
+
+func validateMinRSA2048(bundle []*x509.Certificate) []finding {
+	var findings []finding
+	for i, cert := range bundle {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+			findings = append(findings, finding{certIndex: i + 1,
+				message: fmt.Sprintf("Certificate %s has a %d-bit RSA key", cert.Subject.String(), key.N.BitLen())})
+		}
+	}
+	return findings
+}
+
+func validateLeafValidity(bundle []*x509.Certificate) []finding {
+	var findings []finding
+	for i, cert := range bundle {
+		if cert.IsCA {
+			continue
+		}
+		if days := int(cert.NotAfter.Sub(cert.NotBefore).Hours() / 24); days > 398 {
+			findings = append(findings, finding{certIndex: i + 1,
+				message: fmt.Sprintf("Leaf certificate %s is valid for %d days", cert.Subject.String(), days)})
+		}
+	}
+	return findings
+}
+
+func validateCASubjectKeyID(bundle []*x509.Certificate) []finding {
+	var findings []finding
+	for i, cert := range bundle {
+		if cert.IsCA && len(cert.SubjectKeyId) == 0 {
+			findings = append(findings, finding{certIndex: i + 1,
+				message: fmt.Sprintf("CA certificate %s has no Subject Key Identifier", cert.Subject.String())})
+		}
+	}
+	return findings
+}
+
+// cryptoBackend reports which crypto implementation this binary was built
+// with, since a chain can verify under one and not the other.
+func cryptoBackend() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "GOEXPERIMENT" && strings.Contains(s.Value, "boringcrypto") {
+				return "BoringCrypto (FIPS)"
+			}
+		}
+	}
+	if fips140.Enabled() {
+		return "Go FIPS 140-3 module"
+	}
+	return "Go standard crypto"
+}
+
+// knownCrossSigns adds context for cross-signs whose retirement is well
+// known, keyed by "subject CN <- issuer CN".
+var knownCrossSigns = map[string]string{
+	"ISRG Root X1 <- DST Root CA X3": "Let's Encrypt's legacy compatibility chain; DST Root CA X3 expired 2021-09-30 and the cross-sign 2024-09-30",
+}
+
+// checkCrossSigns finds cross-signed roots: CA certificates carrying the
+// same subject as a root (in the bundle or the public snapshot) but issued
+// by another CA. A chain through one stops validating when the cross-sign
+// or its issuer expires, after which clients need the standalone root.
+func checkCrossSigns(certs []*x509.Certificate, now time.Time) []finding {
+	standalone := map[string]bool{}
+	for _, cert := range certs {
+		if isSelfSigned(cert) {
+			standalone[string(cert.RawSubject)] = true
+		}
+	}
+	publicSubjects := map[string]bool{}
+	for _, r := range publicRootSnapshot {
+		publicSubjects[r.subject] = true
+	}
+
+	var findings []finding
+	header := false
+	for i, cert := range certs {
+		if !cert.IsCA || isSelfSigned(cert) {
+			continue
+		}
+		if !standalone[string(cert.RawSubject)] && !publicSubjects[cert.Subject.String()] {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(out, "=== Cross-Signed Roots ===\n\n")
+			header = true
+		}
+
+		// The cross-sign stops working when either it or its issuer expires
+		eol := cert.NotAfter
+		for _, issuer := range certs {
+			if bytes.Equal(issuer.RawSubject, cert.RawIssuer) && issuer.NotAfter.Before(eol) {
+				eol = issuer.NotAfter
+			}
+		}
+
+		fmt.Fprintf(out, "Certificate #%d: %s\n", i+1, cert.Subject.String())
+		fmt.Fprintf(out, "   Cross-signed by: %s\n", cert.Issuer.String())
+		fmt.Fprintf(out, "   End of life:     %s\n", eol.Format("2006-01-02"))
+		if note, ok := knownCrossSigns[cert.Subject.CommonName+" <- "+cert.Issuer.CommonName]; ok {
+			fmt.Fprintf(out, "   ℹ️  %s\n", note)
+		}
+		if standalone[string(cert.RawSubject)] {
+			fmt.Fprintln(out, "   ✅ Standalone root is also in the bundle; chains survive the cross-sign's end of life")
+			fmt.Fprintln(out)
+			continue
+		}
+		if now.After(eol) {
+			fmt.Fprintln(out, "   ❌ Cross-sign has already reached end of life and the standalone root is missing")
+		} else {
+			fmt.Fprintf(out, "   ⚠️  Standalone root is missing; clients will need it after %s\n", eol.Format("2006-01-02"))
+		}
+		fmt.Fprintln(out)
+		findings = append(findings, finding{ruleID: "cross-sign-eol", certIndex: i + 1,
+			message: fmt.Sprintf("Chain depends on %s cross-signed by %s, which ends on %s; add the standalone root", cert.Subject.String(), cert.Issuer.String(), eol.Format("2006-01-02"))})
+	}
+	return findings
+}
+
+// rootNames implements flag.Value for the repeatable -expected-root flag.
+type rootNames []string
+
+func (r *rootNames) String() string { return strings.Join(*r, ",") }
+
+func (r *rootNames) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// checkExpectedRoots reports, for each intermediate in the bundle, whether
+// the root it chains to is present. Issuers are found by AuthorityKeyId ->
+// SubjectKeyId (see buildChainGraph), so a root that merely shares an
+// expected CommonName does not count. It returns true when an intermediate
+// leads to an expected root that is missing from the bundle.
+//
+// trustStore, when not nil, holds roots the chain may end in without the
+// bundle carrying them, as with a live server that leaves its root out; an
+// expected root found there is reported but is not missing.
+func checkExpectedRoots(certs []*x509.Certificate, expected []string, trustStore *x509.CertPool) ([]finding, bool) {
+	isExpected := map[string]bool{}
+	for _, name := range expected {
+		isExpected[name] = true
+	}
+	g := buildChainGraph(certs)
+
+	fmt.Fprintf(out, "=== Expected Roots: %s ===\n\n", strings.Join(expected, ", "))
+	for _, name := range expected {
+		present := -1
+		for i, cert := range certs {
+			if g.isRoot(i) && cert.Subject.CommonName == name {
+				present = i
+				break
+			}
+		}
+		if present >= 0 {
+			fmt.Fprintf(out, "✅ %s: present (Certificate #%d)\n", name, present+1)
+		} else {
+			fmt.Fprintf(out, "ℹ️  %s: not in bundle\n", name)
+		}
+	}
+	fmt.Fprintln(out)
+
+	var findings []finding
+	missing := false
+	for i, cert := range certs {
+		if !cert.IsCA || g.isRoot(i) {
+			continue
+		}
+
+		// Walk up through any further intermediates to the top of the chain
+		top := i
+		for steps := 0; g.parent[top] >= 0 && !g.isRoot(top) && steps < len(certs); steps++ {
+			top = g.parent[top]
+		}
+
+		fmt.Fprintf(out, "Intermediate %s (Certificate #%d)\n", cert.Subject.String(), i+1)
+		if g.isRoot(top) {
+			root := certs[top]
+			tag := ""
+			if isExpected[root.Subject.CommonName] {
+				tag = " (expected)"
+			}
+			fmt.Fprintf(out, "   ✅ Chains to root %s%s (Certificate #%d)\n\n", root.Subject.String(), tag, top+1)
+			continue
+		}
+
+		issuer := certs[top].Issuer
+		keyID := ""
+		if len(certs[top].AuthorityKeyId) > 0 {
+			keyID = fmt.Sprintf(" with SubjectKeyId %X", certs[top].AuthorityKeyId)
+		}
+		if !isExpected[issuer.CommonName] {
+			fmt.Fprintf(out, "   ℹ️  Issuer %s is not in the bundle and is not an expected root\n\n", issuer.String())
+			continue
+		}
+		if root := trustStoreRoot(certs[top], trustStore); root != nil {
+			fmt.Fprintf(out, "   ℹ️  Expected root %s is not in the chain but is in the trust store\n\n", root.Subject.String())
+			continue
+		}
+		fmt.Fprintf(out, "   ❌ Expected root %s is missing: no root%s in the bundle\n\n", issuer.String(), keyID)
+		findings = append(findings, finding{ruleID: "missing-root", certIndex: i + 1,
+			message: fmt.Sprintf("Intermediate %s chains to expected root %s, which is missing from the bundle; TLS validation will fail for certificates it issued", cert.Subject.String(), issuer.String())})
+		missing = true
+	}
+	return findings, missing
+}
+
+// checkChainVerification verifies each chain end in the bundle (a cert
+// that issued nothing else in it, such as a leaf or the lowest
+// intermediate) with x509.Certificate.Verify, using the bundle's
+// self-signed certs as roots and its other CAs as intermediates. Unlike the
+// name-based ISRG checks, this proves the signatures actually link up.
+// Roots in trustStore, when not nil, are accepted as well, for chains that
+// leave their root out.
+func checkChainVerification(certs []*x509.Certificate, now time.Time, trustStore *x509.CertPool) ([]finding, [][]*x509.Certificate) {
+	roots := x509.NewCertPool()
+	if trustStore != nil {
+		roots = trustStore.Clone()
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		switch {
+		case isSelfSigned(cert):
+			roots.AddCert(cert)
+		case cert.IsCA:
+			intermediates.AddCert(cert)
+		}
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+
+	fmt.Fprintf(out, "=== Chain Verification ===\n\n")
+	g := buildChainGraph(certs)
+	var findings []finding
+	var verifiedChains [][]*x509.Certificate
+	verified := 0
+	for i, cert := range certs {
+		if isSelfSigned(cert) {
+			continue
+		}
+		issuedOther := false
+		for _, p := range g.parent {
+			if p == i {
+				issuedOther = true
+				break
+			}
+		}
+		if issuedOther {
+			continue
+		}
+
+		verified++
+		fmt.Fprintf(out, "Certificate #%d: %s\n", i+1, cert.Subject.String())
+		chains, err := cert.Verify(opts)
+		if err != nil {
+			fmt.Fprintf(out, "   ❌ Does not verify: %v\n\n", err)
+			findings = append(findings, finding{ruleID: "chain-unverified", certIndex: i + 1,
+				message: fmt.Sprintf("Certificate %s does not verify to a trusted root: %v", cert.Subject.String(), err)})
+			continue
+		}
+		var path []string
+		for _, c := range chains[0] {
+			path = append(path, certLabel(c))
+		}
+		fmt.Fprintf(out, "   ✅ Verified: %s\n", strings.Join(path, " → "))
+		if root := chains[0][len(chains[0])-1]; !containsCert(certs, root) {
+			fmt.Fprintf(out, "   ℹ️  Root %s is not in the chain; it was taken from the trust store\n", root.Subject.String())
+		}
+		fmt.Fprintln(out)
+		verifiedChains = append(verifiedChains, chains[0])
+	}
+	if verified == 0 {
+		fmt.Fprintf(out, "ℹ️  Bundle holds only self-signed certificates; no chains to verify\n\n")
+	}
+	return findings, verifiedChains
+}
+
+// trustStoreRoot returns the root in trustStore that cert chains to, or
+// nil if there is none or trustStore is nil. Validity is checked at cert's
+// own NotBefore: expiry is reported on its own, and should not make a root
+// look missing.
+func trustStoreRoot(cert *x509.Certificate, trustStore *x509.CertPool) *x509.Certificate {
+	if trustStore == nil {
+		return nil
+	}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:       trustStore,
+		CurrentTime: cert.NotBefore,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil
+	}
+	return chains[0][len(chains[0])-1]
+}
+
+// containsCert reports whether cert is one of certs.
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeChainFile writes the one chain verification built, leaf to root, as
+// a PEM bundle trimmed of every certificate it did not use. Nothing is
+// written unless verification succeeded.
+func writeChainFile(path string, chains [][]*x509.Certificate, verified bool) error {
+	switch {
+	case !verified:
+		return errors.New("verification failed, so no chain was written")
+	case len(chains) == 0:
+		return errors.New("no chain was verified (the bundle holds only roots)")
+	case len(chains) > 1:
+		return fmt.Errorf("%d chains were verified but only one can be written; pick its leaf with -leaf", len(chains))
+	}
+	var buf bytes.Buffer
+	for _, cert := range chains[0] {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// checkIssuersPresent looks for each non-root certificate's issuer in the
+// bundle, by key ID or DN as buildChainGraph matches them, and reports the
+// gaps. The usual cause is a bundle holding a leaf and a root but not the
+// intermediate between them, whichever CA issued it.
+func checkIssuersPresent(certs []*x509.Certificate) []finding {
+	fmt.Fprintf(out, "=== Issuer Completeness ===\n\n")
+	g := buildChainGraph(certs)
+	var findings []finding
+	for i, cert := range certs {
+		if g.parent[i] != -1 || g.isRoot(i) {
+			continue
+		}
+		fmt.Fprintf(out, "❌ missing issuer for %s: %s\n", cert.Subject.String(), cert.Issuer.String())
+		if len(cert.AuthorityKeyId) > 0 {
+			fmt.Fprintf(out, "   → No certificate in the bundle has SubjectKeyId %X\n", cert.AuthorityKeyId)
+		}
+		findings = append(findings, finding{ruleID: "missing-issuer", certIndex: i + 1,
+			message: fmt.Sprintf("missing issuer for %s: %s", cert.Subject.String(), cert.Issuer.String())})
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "✅ Every certificate's issuer is in the bundle")
+	}
+	fmt.Fprintln(out)
+	return findings
+}
+
+// pathLenDescription renders a certificate's basic constraints for the
+// report. Go uses MaxPathLen -1, or 0 without MaxPathLenZero, for "unset".
+func pathLenDescription(cert *x509.Certificate) string {
+	if !cert.BasicConstraintsValid {
+		return "basic constraints absent"
+	}
+	if !cert.IsCA {
+		return "CA:FALSE"
+	}
+	if cert.MaxPathLen < 0 || (cert.MaxPathLen == 0 && !cert.MaxPathLenZero) {
+		return "CA:TRUE, no path length limit"
+	}
+	return fmt.Sprintf("CA:TRUE, pathlen:%d", cert.MaxPathLen)
+}
+
+// hasPathLenLimit reports whether cert carries a path length constraint.
+func hasPathLenLimit(cert *x509.Certificate) bool {
+	return cert.BasicConstraintsValid && cert.IsCA && (cert.MaxPathLen > 0 || cert.MaxPathLenZero)
+}
+
+// checkBasicConstraints reports each certificate's basic constraints and
+// flags the ones real validation rejects even though the bundle looks
+// structurally fine: issuers that are not CAs, and chains deeper than a
+// CA's path length allows. Self-signed certificates that are not CAs are
+// also flagged, since this tool otherwise treats every one as a root,
+// except for self-signed leaves, which checkSelfSignedLeaves reports.
+func checkBasicConstraints(certs []*x509.Certificate) []finding {
+	fmt.Fprintf(out, "=== Basic Constraints ===\n\n")
+	g := buildChainGraph(certs)
+	var findings []finding
+	for i, cert := range certs {
+		fmt.Fprintf(out, "Certificate #%d: %s\n", i+1, cert.Subject.String())
+		fmt.Fprintf(out, "   IsCA: %v, BasicConstraintsValid: %v, MaxPathLen: %d (%s)\n",
+			cert.IsCA, cert.BasicConstraintsValid, cert.MaxPathLen, pathLenDescription(cert))
+		if g.isRoot(i) && !(cert.BasicConstraintsValid && cert.IsCA) && !isSelfSignedLeaf(g, i) {
+			fmt.Fprintf(out, "   ⚠️  Self-signed but not a CA: usable only as a directly trusted certificate, not as a root\n")
+			findings = append(findings, finding{ruleID: "root-not-ca", certIndex: i + 1,
+				message: fmt.Sprintf("Self-signed certificate %s is not a CA (%s)", cert.Subject.String(), pathLenDescription(cert))})
+		}
+		fmt.Fprintln(out)
+	}
+
+	for i, p := range g.parent {
+		if p < 0 {
+			continue
+		}
+		issuer := certs[p]
+		if issuer.BasicConstraintsValid && issuer.IsCA {
+			continue
+		}
+		fmt.Fprintf(out, "❌ Certificate #%d (%s) is issued by Certificate #%d (%s), which is not a valid CA (%s)\n",
+			i+1, certs[i].Subject.String(), p+1, issuer.Subject.String(), pathLenDescription(issuer))
+		fmt.Fprintln(out, "   → Verifiers reject any chain through it; reissue the issuer with basicConstraints CA:TRUE")
+		findings = append(findings, finding{ruleID: "issuer-not-ca", certIndex: p + 1,
+			message: fmt.Sprintf("Certificate %s issues %s but is not a valid CA (%s)", issuer.Subject.String(), certs[i].Subject.String(), pathLenDescription(issuer))})
+	}
+
+	// Walk up from each chain end, counting the CAs below each ancestor.
+	// A leaf does not count against path length; a CA at the chain end
+	// does, since whatever it issues would sit below it.
+	issuedOther := make([]bool, len(certs))
+	for _, p := range g.parent {
+		if p >= 0 {
+			issuedOther[p] = true
+		}
+	}
+	reported := map[int]bool{}
+	for end := range certs {
+		if issuedOther[end] || g.isRoot(end) {
+			continue
+		}
+		below := 0
+		if certs[end].IsCA {
+			below = 1
+		}
+		for a, steps := g.parent[end], 0; a >= 0 && steps < len(certs); a, steps = g.parent[a], steps+1 {
+			ancestor := certs[a]
+			if hasPathLenLimit(ancestor) && below > ancestor.MaxPathLen && !reported[a] {
+				reported[a] = true
+				fmt.Fprintf(out, "❌ Certificate #%d (%s) allows %d intermediates below it, but the chain to %s has %d\n",
+					a+1, ancestor.Subject.String(), ancestor.MaxPathLen, certs[end].Subject.String(), below)
+				findings = append(findings, finding{ruleID: "path-length-exceeded", certIndex: a + 1,
+					message: fmt.Sprintf("Certificate %s has pathlen:%d but %d intermediates sit below it in the chain to %s",
+						ancestor.Subject.String(), ancestor.MaxPathLen, below, certs[end].Subject.String())})
+			}
+			// Self-issued intermediates, such as key rollovers, do not
+			// count towards path length (RFC 5280, section 4.2.1.9)
+			if !bytes.Equal(ancestor.RawSubject, ancestor.RawIssuer) {
+				below++
+			}
+		}
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "✅ Every issuer is a CA and no path length constraint is exceeded")
+	}
+	fmt.Fprintln(out)
+	return findings
+}
+
+// isSelfSignedLeaf reports whether cert i is a server's own snakeoil
+// certificate rather than a root: self-signed, issuing nothing in the
+// bundle, and either explicitly CA:FALSE or carrying server names. Old v1
+// roots without basic constraints do not qualify.
+func isSelfSignedLeaf(g *chainGraph, i int) bool {
+	cert := g.certs[i]
+	if !g.isRoot(i) || !g.isLeaf(i) {
+		return false
+	}
+	return cert.BasicConstraintsValid || len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0
+}
+
+// checkSelfSignedLeaves reports self-signed leaf certificates. They verify
+// only when trusted directly, so a bundle of them has no chain at all.
+func checkSelfSignedLeaves(certs []*x509.Certificate) []finding {
+	g := buildChainGraph(certs)
+	var findings []finding
+	for i, cert := range certs {
+		if !isSelfSignedLeaf(g, i) {
+			continue
+		}
+		if findings == nil {
+			fmt.Fprintf(out, "=== Self-Signed Leaf Certificates ===\n\n")
+		}
+		fmt.Fprintf(out, "⚠️  Certificate #%d: %s\n", i+1, cert.Subject.String())
+		fmt.Fprintln(out, "   Self-signed but not a CA: a leaf certificate, not a root")
+		if len(cert.DNSNames) > 0 {
+			fmt.Fprintf(out, "   Issued for: %s\n", strings.Join(cert.DNSNames, ", "))
+		}
+		fmt.Fprintln(out, "   → No real chain exists; clients must trust this exact certificate, and it must be replaced when it expires")
+		fmt.Fprintln(out)
+		findings = append(findings, finding{ruleID: "self-signed-leaf", certIndex: i + 1,
+			message: fmt.Sprintf("Certificate %s is a self-signed leaf, not a CA; no chain exists", cert.Subject.String())})
+	}
+	return findings
+}
+
+// publicRoot is a publicly trusted root, identified by the SHA-256
+// fingerprint of its DER encoding.
+type publicRoot struct {
+	fingerprint string // uppercase hex, no separators
+	subject     string
+	notAfter    string // YYYY-MM-DD; empty for roots loaded from -public-roots-file
+}
+
+// publicRootSnapshotReviewed is when publicRootSnapshot was last checked
+// against its source bundle.
+var publicRootSnapshotReviewed = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+// publicRootSnapshot is a curated set of widely trusted public roots, taken
+// from Mozilla CA bundle version 2.60 (generated from NSS certdata.txt) as
+// shipped in Debian ca-certificates 20230311+deb12u1. Roots that have since
+// expired (Baltimore CyberTrust Root) or been distrusted (Entrust) are left
+// out. It covers the CAs commonly seen on public OAuth endpoints and is not
+// exhaustive; use -public-roots-file for a complete or newer list.
+//
+// The list was last reviewed on publicRootSnapshotReviewed, when every root
+// in it was unexpired. Roots expire on their own schedule afterwards, so
+// checkPublicRoots ignores any whose notAfter has passed.
+var publicRootSnapshot = []publicRoot{
+	{"8ECDE6884F3D87B1125BA31AC3FCB13D7016DE7F57CC904FE1CB97C6AE98196E", "CN=Amazon Root CA 1,O=Amazon,C=US", "2038-01-17"},
+	{"1BA5B2AA8C65401A82960118F80BEC4F62304D83CEC4713A19C39C011EA46DB4", "CN=Amazon Root CA 2,O=Amazon,C=US", "2040-05-26"},
+	{"18CE6CFE7BF14E60B2E347B8DFE868CB31D02EBB3ADA271569F50343B46DB3A4", "CN=Amazon Root CA 3,O=Amazon,C=US", "2040-05-26"},
+	{"E35D28419ED02025CFA69038CD623962458DA5C695FBDEA3C22B0BFB25897092", "CN=Amazon Root CA 4,O=Amazon,C=US", "2040-05-26"},
+	{"5C58468D55F58E497E743982D2B50010B6D165374ACF83A7D4A32DB768C4408E", "CN=Certum Trusted Network CA,OU=Certum Certification Authority,O=Unizeto Technologies S.A.,C=PL", "2029-12-31"},
+	{"4348A0E9444C78CB265E058D5E8944B4D84F9662BD26DB257F8934A443C70161", "CN=DigiCert Global Root CA,OU=www.digicert.com,O=DigiCert Inc,C=US", "2031-11-10"},
+	{"CB3CCBB76031E5E0138F8DD39A23F9DE47FFC35E43C1144CEA27D46A5AB1CB5F", "CN=DigiCert Global Root G2,OU=www.digicert.com,O=DigiCert Inc,C=US", "2038-01-15"},
+	{"31AD6648F8104138C738F39EA4320133393E3A18CC02296EF97C2AC9EF6731D0", "CN=DigiCert Global Root G3,OU=www.digicert.com,O=DigiCert Inc,C=US", "2038-01-15"},
+	{"7431E5F4C3C1CE4690774F0B61E05440883BA9A01ED00BA6ABD7806ED3B118CF", "CN=DigiCert High Assurance EV Root CA,OU=www.digicert.com,O=DigiCert Inc,C=US", "2031-11-10"},
+	{"D947432ABDE7B7FA90FC2E6B59101B1280E0E1C7E4E40FA3C6887FFF57A7F4CF", "CN=GTS Root R1,O=Google Trust Services LLC,C=US", "2036-06-22"},
+	{"8D25CD97229DBF70356BDA4EB3CC734031E24CF00FAFCFD32DC76EB5841C7EA8", "CN=GTS Root R2,O=Google Trust Services LLC,C=US", "2036-06-22"},
+	{"34D8A73EE208D9BCDB0D956520934B4E40E69482596E8B6F73C8426B010A6F48", "CN=GTS Root R3,O=Google Trust Services LLC,C=US", "2036-06-22"},
+	{"349DFA4058C5E263123B398AE795573C4E1313C83FE68F93556CD5E8031B3C7D", "CN=GTS Root R4,O=Google Trust Services LLC,C=US", "2036-06-22"},
+	{"EBD41040E4BB3EC742C9E381D31EF2A41A48B6685C96E7CEF3C1DF6CD4331C99", "CN=GlobalSign Root CA,OU=Root CA,O=GlobalSign nv-sa,C=BE", "2028-01-28"},
+	{"CBB9C44D84B8043E1050EA31A69F514955D7BFD2E2C6B49301019AD61D9F5058", "CN=GlobalSign Root E46,O=GlobalSign nv-sa,C=BE", "2046-03-20"},
+	{"4FA3126D8D3A11D1C4855A4F807CBAD6CF919D3A5A88B03BEA2C6372D93C40C9", "CN=GlobalSign Root R46,O=GlobalSign nv-sa,C=BE", "2046-03-20"},
+	{"B085D70B964F191A73E4AF0D54AE7A0E07AAFDAF9B71DD0862138AB7325A24A2", "CN=GlobalSign,OU=GlobalSign ECC Root CA - R4,O=GlobalSign", "2038-01-19"},
+	{"179FBC148A3DD00FD24EA13458CC43BFA7F59C8182D783A513F6EBEC100C8924", "CN=GlobalSign,OU=GlobalSign ECC Root CA - R5,O=GlobalSign", "2038-01-19"},
+	{"CBB522D7B7F127AD6A0113865BDF1CD4102E7D0759AF635A7CF4720DC963C53B", "CN=GlobalSign,OU=GlobalSign Root CA - R3,O=GlobalSign", "2029-03-18"},
+	{"2CABEAFE37D06CA22ABA7391C0033D25982952C453647349763A3AB5AD6CCF69", "CN=GlobalSign,OU=GlobalSign Root CA - R6,O=GlobalSign", "2034-12-10"},
+	{"45140B3247EB9CC8C5B4F0D7B53091F73292089E6E5A63E2749DD3ACA9198EDA", "CN=Go Daddy Root Certificate Authority - G2,O=GoDaddy.com\\, Inc.,L=Scottsdale,ST=Arizona,C=US", "2037-12-31"},
+	{"96BCEC06264976F37460779ACF28C5A7CFE8A3C0AAE11A8FFCEE05C0BDDF08C6", "CN=ISRG Root X1,O=Internet Security Research Group,C=US", "2035-06-04"},
+	{"69729B8E15A86EFC177A57AFB7171DFC64ADD28C2FCA8CF1507E34453CCB1470", "CN=ISRG Root X2,O=Internet Security Research Group,C=US", "2040-09-17"},
+	{"358DF39D764AF9E1B766E9C972DF352EE15CFAC227AF6AD1D70E8E4A6EDCBA02", "CN=Microsoft ECC Root Certificate Authority 2017,O=Microsoft Corporation,C=US", "2042-07-18"},
+	{"C741F70F4B2A8D88BF2E71C14122EF53EF10EBA0CFA5E64CFA20F418853073E0", "CN=Microsoft RSA Root Certificate Authority 2017,O=Microsoft Corporation,C=US", "2042-07-18"},
+	{"568D6905A2C88708A4B3025190EDCFEDB1974A606A13C6E5290FCB2AE63EDAB5", "CN=Starfield Services Root Certificate Authority - G2,O=Starfield Technologies\\, Inc.,L=Scottsdale,ST=Arizona,C=US", "2037-12-31"},
+	{"4FF460D54B9C86DABFBCFC5712E0400D2BED3FBC4D4FBDAA86E06ADCD2A9AD7A", "CN=USERTrust ECC Certification Authority,O=The USERTRUST Network,L=Jersey City,ST=New Jersey,C=US", "2038-01-18"},
+	{"E793C9B02FD8AA13E21C31228ACCB08119643B749C898964B1746D46C3D4CBD2", "CN=USERTrust RSA Certification Authority,O=The USERTRUST Network,L=Jersey City,ST=New Jersey,C=US", "2038-01-18"},
+}
+
+// loadPublicRoots reads a fingerprint list: one SHA-256 fingerprint per
+// line (colons allowed), optionally followed by the subject. Blank lines
+// and lines starting with # are ignored.
+func loadPublicRoots(path string) ([]publicRoot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var roots []publicRoot
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fp, subject, _ := strings.Cut(line, " ")
+		fp = strings.ToUpper(strings.ReplaceAll(fp, ":", ""))
+		if len(fp) != 64 {
+			return nil, fmt.Errorf("line %d: %q is not a SHA-256 fingerprint", n+1, fp)
+		}
+		roots = append(roots, publicRoot{fingerprint: fp, subject: strings.TrimSpace(subject)})
+	}
+	return roots, nil
+}
+
+// checkPublicRoots classifies the top of each chain in the bundle. A
+// self-signed root is matched by fingerprint; a chain whose root is not in
+// the bundle can only be matched by its issuer's name, which is reported as
+// such. Roots in snapshot that expired before now no longer count.
+func checkPublicRoots(g *chainGraph, snapshot []publicRoot, source string, now time.Time) []finding {
+	fmt.Fprintln(out, "=== Public Trust ===")
+	live, expired := unexpiredRoots(snapshot, now)
+	if expired > 0 {
+		fmt.Fprintf(out, "Reference: %s (%d roots, %d expired and ignored)\n\n", source, len(live), expired)
+	} else {
+		fmt.Fprintf(out, "Reference: %s (%d roots)\n\n", source, len(live))
+	}
+
+	byFingerprint := map[string]publicRoot{}
+	bySubject := map[string]publicRoot{}
+	for _, r := range live {
+		byFingerprint[r.fingerprint] = r
+		if r.subject != "" {
+			bySubject[r.subject] = r
+		}
+	}
+
+	var findings []finding
+	for i, cert := range g.certs {
+		if g.parent[i] != -1 {
+			continue
+		}
+		sum := sha256.Sum256(cert.Raw)
+		if g.isRoot(i) {
+			if _, ok := byFingerprint[strings.ToUpper(hex.EncodeToString(sum[:]))]; ok {
+				fmt.Fprintf(out, "✅ %s: publicly trusted (per %s)\n", cert.Subject.String(), source)
+				continue
+			}
+			fmt.Fprintf(out, "ℹ️  %s: NOT publicly trusted (private or unknown root)\n", cert.Subject.String())
+			findings = append(findings, finding{ruleID: "not-publicly-trusted", certIndex: i + 1,
+				message: fmt.Sprintf("Root %s is not in the public roots %s", cert.Subject.String(), source)})
+			continue
+		}
+		if _, ok := bySubject[cert.Issuer.String()]; ok {
+			fmt.Fprintf(out, "✅ %s: issued by public root %s (matched by name; root not in bundle)\n", cert.Subject.String(), cert.Issuer.String())
+			continue
+		}
+		fmt.Fprintf(out, "ℹ️  %s: issuer %s is not a known public root\n", cert.Subject.String(), cert.Issuer.String())
+		findings = append(findings, finding{ruleID: "not-publicly-trusted", certIndex: i + 1,
+			message: fmt.Sprintf("Chain for %s ends at %s, which is not in the public roots %s", cert.Subject.String(), cert.Issuer.String(), source)})
+	}
+	fmt.Fprintln(out)
+	return findings
+}
+
+// unexpiredRoots returns the roots in snapshot still valid at now, and how
+// many were dropped. Roots without a notAfter are kept.
+func unexpiredRoots(snapshot []publicRoot, now time.Time) ([]publicRoot, int) {
+	var live []publicRoot
+	for _, r := range snapshot {
+		if notAfter, err := time.Parse("2006-01-02", r.notAfter); err == nil && now.After(notAfter) {
+			continue
+		}
+		live = append(live, r)
+	}
+	return live, len(snapshot) - len(live)
+}
+
+// scoreFactor is one weighted component of the trust score. pass is the
+// fraction of the factor satisfied, from 0 to 1.
+type scoreFactor struct {
+	name   string
+	weight int
+	pass   float64
+}
+
+// trustScore summarizes bundle quality as 0-100 for dashboards. Each factor
+// earns its weight in proportion to how many certs satisfy it, so one bad
+// cert in a large bundle costs less than in a small one.
+func trustScore(g *chainGraph, findings []finding, trusted []publicRoot) int {
+	total := len(g.certs)
+	ratio := func(bad int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(total-bad) / float64(total)
+	}
+
+	countRule := func(ruleID string) int {
+		n := 0
+		for _, f := range findings {
+			if f.ruleID == ruleID {
+				n++
+			}
+		}
+		return n
+	}
+
+	incomplete := 0
+	anchors, trustedAnchors := 0, 0
+	known := map[string]bool{}
+	for _, r := range trusted {
+		known[r.fingerprint] = true
+	}
+	seen := map[[sha256.Size]byte]bool{}
+	duplicates := 0
+	for i, cert := range g.certs {
+		sum := sha256.Sum256(cert.Raw)
+		if seen[sum] {
+			duplicates++
+		}
+		seen[sum] = true
+
+		if g.parent[i] != -1 {
+			continue
+		}
+		if !g.isRoot(i) {
+			incomplete++
+			continue
+		}
+		anchors++
+		if known[strings.ToUpper(hex.EncodeToString(sum[:]))] {
+			trustedAnchors++
+		}
+	}
+	rootsTrusted := 0.0
+	if anchors > 0 {
+		rootsTrusted = float64(trustedAnchors) / float64(anchors)
+	}
+
+	factors := []scoreFactor{
+		{"Complete chains", 30, ratio(incomplete)},
+		{"No expired certificates", 20, ratio(countRule("expired-cert"))},
+		{"No weak signature algorithms", 15, ratio(countRule("weak-signature"))},
+		{"No duplicates", 10, ratio(duplicates)},
+		{"Roots publicly trusted or known internal", 25, rootsTrusted},
+	}
+
+	fmt.Fprintln(out, "=== Trust Score ===")
+	fmt.Fprintln(out)
+	score := 0.0
+	for _, f := range factors {
+		points := float64(f.weight) * f.pass
+		score += points
+		fmt.Fprintf(out, "  %-42s %5.1f / %d\n", f.name, points, f.weight)
+	}
+	result := int(score + 0.5)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Score: %d / 100\n\n", result)
+	return result
+}
+
+// fileList implements flag.Value for the repeatable -leaf flag.
+type fileList []string
+
+func (f *fileList) String() string { return strings.Join(*f, ",") }
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// parseCerts parses every certificate in PEM data, or in concatenated DER
+// when there is no PEM block at all.
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	sawPEM := false
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		sawPEM = true
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if !sawPEM {
+		return x509.ParseCertificates(data)
+	}
+	return certs, nil
+}
+
+// verifyLeaves verifies each leaf file independently against the bundle:
+// its self-signed certs are the roots and its other CAs the intermediates,
+// as in checkChainVerification. Certificates after the first in a leaf
+// file, such as the chain in a cert-manager tls.crt, are added as
+// intermediates for that leaf only.
+func verifyLeaves(bundle []*x509.Certificate, leafFiles []string, now time.Time) ([][]*x509.Certificate, bool) {
+	roots := x509.NewCertPool()
+	var bundleIntermediates []*x509.Certificate
+	for _, cert := range bundle {
+		switch {
+		case isSelfSigned(cert):
+			roots.AddCert(cert)
+		case cert.IsCA:
+			bundleIntermediates = append(bundleIntermediates, cert)
+		}
+	}
+
+	fmt.Fprintf(out, "=== Verifying %d Leaf Certificates ===\n", len(leafFiles))
+	fmt.Fprintf(out, "Bundle: %d certificates\n\n", len(bundle))
+	var verifiedChains [][]*x509.Certificate
+	failed := 0
+	for _, path := range leafFiles {
+		fmt.Fprintf(out, "--- Leaf: %s ---\n", path)
+		data, err := readBundle(path)
+		if err != nil {
+			fmt.Fprintf(out, "❌ Cannot read: %v\n\n", err)
+			failed++
+			continue
+		}
+		certs, err := parseCerts(data)
+		if err == nil && len(certs) == 0 {
+			err = errors.New("no certificates found")
+		}
+		if err != nil {
+			fmt.Fprintf(out, "❌ Cannot parse: %v\n\n", err)
+			failed++
+			continue
+		}
+
+		leaf := certs[0]
+		fmt.Fprintf(out, "Subject: %s\n", leaf.Subject.String())
+		fmt.Fprintf(out, "Issuer:  %s\n", leaf.Issuer.String())
+		if len(leaf.DNSNames) > 0 {
+			fmt.Fprintf(out, "DNS:     %s\n", strings.Join(leaf.DNSNames, ", "))
+		}
+		fmt.Fprintf(out, "Expires: %s\n", leaf.NotAfter.Format("2006-01-02"))
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range bundleIntermediates {
+			intermediates.AddCert(cert)
+		}
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if len(certs) > 1 {
+			fmt.Fprintf(out, "Chain in file: %d more certificates, used as intermediates\n", len(certs)-1)
+		}
+
+		chains, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			fmt.Fprintf(out, "❌ Does not verify: %v\n", err)
+			var unknown x509.UnknownAuthorityError
+			if errors.As(err, &unknown) {
+				fmt.Fprintf(out, "   → Neither the bundle nor the leaf file provides a path from %s to a root in the bundle\n", leaf.Issuer.String())
+			}
+			fmt.Fprintln(out)
+			failed++
+			continue
+		}
+		var path []string
+		for _, c := range chains[0] {
+			path = append(path, certLabel(c))
+		}
+		fmt.Fprintf(out, "✅ Verified: %s\n\n", strings.Join(path, " → "))
+		verifiedChains = append(verifiedChains, chains[0])
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(out, "❌ %d of %d leaf certificates do not verify against the bundle\n", failed, len(leafFiles))
+		return verifiedChains, false
+	}
+	fmt.Fprintf(out, "✅ All %d leaf certificates verify against the bundle\n", len(leafFiles))
+	return verifiedChains, true
+}
+
+// readFirstCert reads the first certificate from a PEM or DER file.
+func readFirstCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return x509.ParseCertificate(data)
+}
+
+// verifyIssuedBy checks that parent signed child, and whether the child's
+// issuer name and AuthorityKeyId point at the parent, to pinpoint exactly
+// where a chain breaks.
+func verifyIssuedBy(childPath, parentPath string) bool {
+	child, err := readFirstCert(childPath)
+	if err != nil {
+		fmt.Printf("Error reading child certificate: %v\n", err)
+		return false
+	}
+	parent, err := readFirstCert(parentPath)
+	if err != nil {
+		fmt.Printf("Error reading parent certificate: %v\n", err)
+		return false
+	}
+
+	fmt.Printf("=== Verify Issued By ===\n\n")
+	fmt.Printf("Child:  %s\n", child.Subject.String())
+	fmt.Printf("        issued by %s\n", child.Issuer.String())
+	fmt.Printf("Parent: %s\n\n", parent.Subject.String())
+
+	if bytes.Equal(child.RawIssuer, parent.RawSubject) {
+		fmt.Println("✅ Child's Issuer matches parent's Subject")
+	} else {
+		fmt.Println("⚠️  Child's Issuer does NOT match parent's Subject (byte-for-byte)")
+	}
+
+	switch {
+	case len(child.AuthorityKeyId) == 0:
+		fmt.Println("ℹ️  Child has no AuthorityKeyId")
+	case len(parent.SubjectKeyId) == 0:
+		fmt.Println("ℹ️  Parent has no SubjectKeyId")
+	case bytes.Equal(child.AuthorityKeyId, parent.SubjectKeyId):
+		fmt.Printf("✅ AuthorityKeyId matches parent's SubjectKeyId (%X)\n", parent.SubjectKeyId)
+	default:
+		fmt.Printf("⚠️  AuthorityKeyId %X does NOT match parent's SubjectKeyId %X\n", child.AuthorityKeyId, parent.SubjectKeyId)
+	}
+
+	if err := child.CheckSignatureFrom(parent); err != nil {
+		fmt.Printf("❌ Signature does NOT verify: %v\n", err)
+		return false
+	}
+	fmt.Println("✅ Signature verifies: parent issued child")
+	return true
+}
+
+// ocspTimeout bounds each request to an OCSP responder.
+const ocspTimeout = 10 * time.Second
+
+// queryOCSP asks server for cert's status and verifies the response was
+// signed by the issuer or by a responder the issuer delegated to.
+func queryOCSP(server string, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	body, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: ocspTimeout}
+	resp, err := client.Post(server, "application/ocsp-request", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responder returned HTTP %d", resp.StatusCode)
+	}
+	der, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseOCSPResponse(der, cert, issuer)
+}
+
+// parseOCSPResponse extracts cert's status from a DER OCSP response and
+// checks who signed it. ocsp.ParseResponseForCert is given no issuer
+// because it would reject a response that embeds the issuer itself; the
+// signer is checked here instead. A delegated responder must be issued by
+// the same CA and carry the OCSPSigning EKU.
+func parseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	resp, err := ocsp.ParseResponseForCert(der, cert, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.Certificate == nil:
+		if err := resp.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("OCSP response signature does not verify: %w", err)
+		}
+	case resp.Certificate.Equal(issuer):
+		// ParseResponseForCert already checked the signature against it.
+	default:
+		responder := resp.Certificate
+		if err := responder.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("responder certificate %s was not issued by %s: %w", responder.Subject, issuer.Subject, err)
+		}
+		delegated := false
+		for _, eku := range responder.ExtKeyUsage {
+			if eku == x509.ExtKeyUsageOCSPSigning {
+				delegated = true
+			}
+		}
+		if !delegated {
+			return nil, fmt.Errorf("responder certificate %s lacks the OCSPSigning EKU", responder.Subject)
+		}
+	}
+	return resp, nil
+}
+
+// checkRevocation queries the OCSP responder of every certificate whose
+// issuer is also in the bundle. Failing to reach a responder is reported
+// separately from a revocation, since it says nothing about the cert.
+func checkRevocation(certs []*x509.Certificate) []finding {
+	fmt.Fprintf(out, "=== OCSP Revocation ===\n\n")
+	g := buildChainGraph(certs)
+	var findings []finding
+	for i, cert := range certs {
+		if g.isRoot(i) {
+			continue
+		}
+		fmt.Fprintf(out, "Certificate #%d: %s\n", i+1, cert.Subject.String())
+		if len(cert.OCSPServer) == 0 {
+			fmt.Fprintf(out, "   ℹ️  No OCSP responder URL; skipped\n\n")
+			continue
+		}
+		if g.parent[i] < 0 {
+			fmt.Fprintf(out, "   ℹ️  Issuer %s is not in the bundle; cannot build the OCSP request\n\n", cert.Issuer.String())
+			continue
+		}
+		issuer := certs[g.parent[i]]
+		server := cert.OCSPServer[0]
+		fmt.Fprintf(out, "   Responder: %s\n", server)
+
+		status, err := queryOCSP(server, cert, issuer)
+		if err != nil {
+			fmt.Fprintf(out, "   ⚠️  Could not check revocation: %v\n\n", err)
+			findings = append(findings, finding{ruleID: "ocsp-unavailable", certIndex: i + 1,
+				message: fmt.Sprintf("OCSP status of %s could not be determined from %s: %v", cert.Subject.String(), server, err)})
+			continue
+		}
+		switch status.Status {
+		case ocsp.Good:
+			fmt.Fprintf(out, "   ✅ Good (as of %s)\n", status.ThisUpdate.Format(time.RFC3339))
+		case ocsp.Revoked:
+			fmt.Fprintf(out, "   ❌ REVOKED on %s\n", status.RevokedAt.Format(time.RFC3339))
+			findings = append(findings, finding{ruleID: "ocsp-revoked", certIndex: i + 1,
+				message: fmt.Sprintf("Certificate %s was revoked on %s", cert.Subject.String(), status.RevokedAt.Format("2006-01-02"))})
+		default:
+			fmt.Fprintf(out, "   ⚠️  Unknown: the responder has no status for this certificate\n")
+			findings = append(findings, finding{ruleID: "ocsp-unknown", certIndex: i + 1,
+				message: fmt.Sprintf("OCSP responder %s does not know certificate %s", server, cert.Subject.String())})
+		}
+		if !status.NextUpdate.IsZero() && time.Now().After(status.NextUpdate) {
+			fmt.Fprintf(out, "   ⚠️  Response is stale (next update was due %s)\n", status.NextUpdate.Format(time.RFC3339))
+		}
+		fmt.Fprintln(out)
+	}
+	return findings
+}
+
+// checkCRL checks the bundle against a CRL, for PKIs without OCSP. Serial
+// numbers are only unique per issuer, so only certificates issued by the
+// CRL's issuer are looked up. A CRL whose signature does not verify is not
+// trusted at all; one whose issuer is not in the bundle is used, but
+// reported as unverified.
+func checkCRL(certs []*x509.Certificate, path string, now time.Time) ([]finding, error) {
+	data, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("%s: expected an X509 CRL PEM block, found %s", path, block.Type)
+		}
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	fmt.Fprintf(out, "=== CRL Revocation ===\n\n")
+	fmt.Fprintf(out, "CRL: %s\n", path)
+	fmt.Fprintf(out, "   Issuer: %s\n", crl.Issuer.String())
+	fmt.Fprintf(out, "   This update: %s\n", crl.ThisUpdate.Format(time.RFC3339))
+	fmt.Fprintf(out, "   Revoked entries: %d\n", len(crl.RevokedCertificateEntries))
+
+	var findings []finding
+	var issuer *x509.Certificate
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawSubject, crl.RawIssuer) && crl.CheckSignatureFrom(cert) == nil {
+			issuer = cert
+			break
+		}
+	}
+	if issuer == nil {
+		for _, cert := range certs {
+			if bytes.Equal(cert.RawSubject, crl.RawIssuer) {
+				fmt.Fprintf(out, "   ❌ Signature does not verify against %s; CRL entries ignored\n\n", certLabel(cert))
+				findings = append(findings, finding{ruleID: "crl-signature-invalid",
+					message: fmt.Sprintf("CRL %s is not validly signed by %s in the bundle", path, cert.Subject.String())})
+				return findings, nil
+			}
+		}
+		fmt.Fprintf(out, "   ⚠️  Issuer not in the bundle; signature NOT verified\n")
+		findings = append(findings, finding{ruleID: "crl-issuer-missing",
+			message: fmt.Sprintf("Issuer %s of CRL %s is not in the bundle, so its signature was not checked", crl.Issuer.String(), path)})
+	} else {
+		fmt.Fprintf(out, "   ✅ Signature verified by %s\n", certLabel(issuer))
+	}
+
+	switch {
+	case crl.NextUpdate.IsZero():
+		fmt.Fprintf(out, "   ⚠️  No next update date; cannot tell whether the CRL is current\n")
+	case now.After(crl.NextUpdate):
+		fmt.Fprintf(out, "   ⚠️  EXPIRED: next update was due %s; fetch a fresh CRL\n", crl.NextUpdate.Format(time.RFC3339))
+		findings = append(findings, finding{ruleID: "crl-expired",
+			message: fmt.Sprintf("CRL %s expired on %s", path, crl.NextUpdate.Format("2006-01-02"))})
+	default:
+		fmt.Fprintf(out, "   ✅ Current until %s\n", crl.NextUpdate.Format(time.RFC3339))
+	}
+	fmt.Fprintln(out)
+
+	checked := 0
+	for i, cert := range certs {
+		if !bytes.Equal(cert.RawIssuer, crl.RawIssuer) || isSelfSigned(cert) {
+			continue
+		}
+		checked++
+		fmt.Fprintf(out, "Certificate #%d: %s\n", i+1, cert.Subject.String())
+		revoked := false
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				fmt.Fprintf(out, "   ❌ REVOKED on %s\n\n", entry.RevocationTime.Format(time.RFC3339))
+				findings = append(findings, finding{ruleID: "crl-revoked", certIndex: i + 1,
+					message: fmt.Sprintf("Certificate %s was revoked on %s according to CRL %s", cert.Subject.String(), entry.RevocationTime.Format("2006-01-02"), path)})
+				revoked = true
+				break
+			}
+		}
+		if !revoked {
+			fmt.Fprintf(out, "   ✅ Not revoked\n\n")
+		}
+	}
+	if checked == 0 {
+		fmt.Fprintf(out, "ℹ️  No certificate in the bundle was issued by the CRL's issuer\n\n")
+	}
+	return findings, nil
+}
@@ -0,0 +1,982 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// fixtureNow is well inside the validity of every fixture except the
+// deliberately expired R3 intermediate.
+var fixtureNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestAnalyzeLetsEncrypt(t *testing.T) {
+	tests := []struct {
+		bundle            string
+		foundRoot         bool
+		foundIntermediate bool
+		chainComplete     bool
+		problems          []string
+	}{
+		{"complete", true, true, true, nil},
+		{"intermediate-without-root", false, true, false, []string{"Let's Encrypt intermediate is present but ISRG Root X1 is not"}},
+		{"no-letsencrypt", false, false, false, nil},
+		{"duplicate-root", true, true, true, []string{"ISRG Root X1 appears 2 times"}},
+		{"expired-intermediate", true, true, false, []string{"Let's Encrypt intermediate R3 expired on 2021-09-15"}},
+		{"key-id-mismatch", true, true, false, []string{"Let's Encrypt intermediate R13 names ISRG Root X1 as issuer but its AuthorityKeyId 0A194B460EC4DB8A98ABD8887FE9540304223983 matches no ISRG Root X1 in the bundle"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bundle, func(t *testing.T) {
+			le := analyzeLetsEncrypt(loadFixture(t, tt.bundle), fixtureNow)
+			if le.foundRoot != tt.foundRoot {
+				t.Errorf("foundRoot = %v, want %v", le.foundRoot, tt.foundRoot)
+			}
+			if le.foundIntermediate != tt.foundIntermediate {
+				t.Errorf("foundIntermediate = %v, want %v", le.foundIntermediate, tt.foundIntermediate)
+			}
+			if le.chainComplete != tt.chainComplete {
+				t.Errorf("chainComplete = %v, want %v", le.chainComplete, tt.chainComplete)
+			}
+			if !reflect.DeepEqual(le.problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", le.problems, tt.problems)
+			}
+		})
+	}
+}
+
+// TestPrintLetsEncryptAnalysis compares the Trust Chain Analysis section
+// against testdata; run with -update after an intended wording change.
+func TestPrintLetsEncryptAnalysis(t *testing.T) {
+	bundles := []string{"complete", "intermediate-without-root", "no-letsencrypt", "duplicate-root", "expired-intermediate", "key-id-mismatch"}
+	for _, bundle := range bundles {
+		t.Run(bundle, func(t *testing.T) {
+			var buf bytes.Buffer
+			saved := out
+			out = &buf
+			defer func() { out = saved }()
+			printLetsEncryptAnalysis(analyzeLetsEncrypt(loadFixture(t, bundle), fixtureNow))
+
+			golden := filepath.Join("testdata", bundle+".golden")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("output differs from %s:\n--- got ---\n%s--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func loadFixture(t *testing.T, bundle string) []*x509.Certificate {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", bundle+".pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := parseCerts(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certs
+}
+
+// TestPublicRootSnapshotUnexpired checks the snapshot as of its review
+// date: no root in it may have already expired then. Expiry after that is
+// handled at runtime by checkPublicRoots, so the test does not depend on
+// the current date.
+func TestPublicRootSnapshotUnexpired(t *testing.T) {
+	now := publicRootSnapshotReviewed
+	seen := map[string]bool{}
+	for _, root := range publicRootSnapshot {
+		if len(root.fingerprint) != 64 || strings.ToUpper(root.fingerprint) != root.fingerprint {
+			t.Errorf("%s: fingerprint %q is not 64 uppercase hex digits", root.subject, root.fingerprint)
+		}
+		if seen[root.fingerprint] {
+			t.Errorf("%s: listed twice", root.subject)
+		}
+		seen[root.fingerprint] = true
+		notAfter, err := time.Parse("2006-01-02", root.notAfter)
+		if err != nil {
+			t.Errorf("%s: bad notAfter %q: %v", root.subject, root.notAfter, err)
+			continue
+		}
+		if now.After(notAfter) {
+			t.Errorf("%s expired on %s, before the snapshot was reviewed; drop it from publicRootSnapshot", root.subject, root.notAfter)
+		}
+	}
+}
+
+// TestCheckPublicRootsExpired checks that a snapshot root past its
+// notAfter no longer makes a bundle root publicly trusted.
+func TestCheckPublicRootsExpired(t *testing.T) {
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	root, _ := newTestCA(t, "Test Root", nil, nil, 1)
+	sum := sha256.Sum256(root.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+	g := buildChainGraph([]*x509.Certificate{root})
+
+	for _, tt := range []struct {
+		notAfter string
+		trusted  bool
+	}{
+		{"2030-01-01", true},
+		{"2025-05-31", false},
+		{"", true},
+	} {
+		snapshot := []publicRoot{{fingerprint: fingerprint, subject: root.Subject.String(), notAfter: tt.notAfter}}
+		findings := checkPublicRoots(g, snapshot, "test snapshot", fixtureNow)
+		if trusted := len(findings) == 0; trusted != tt.trusted {
+			t.Errorf("notAfter %q: publicly trusted = %v, want %v (findings %+v)", tt.notAfter, trusted, tt.trusted, findings)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	f := func(ruleIDs ...string) []finding {
+		var findings []finding
+		for _, id := range ruleIDs {
+			findings = append(findings, finding{ruleID: id})
+		}
+		return findings
+	}
+	tests := []struct {
+		name        string
+		findings    []finding
+		parseErrors int
+		rootMissing bool
+		want        int
+	}{
+		{"clean", nil, 0, false, 0},
+		{"warnings only", f("missing-issuer", "root-purpose", "key-id-mismatch"), 0, false, 0},
+		{"expired intermediate", f("expired-intermediate"), 0, false, exitExpiredIntermediate},
+		{"unverifiable chain", f("chain-unverified"), 0, false, exitChainUnverified},
+		{"path length violation", f("path-length-exceeded"), 0, false, exitPathLength},
+		{"expired intermediate also fails verification", f("chain-unverified", "expired-intermediate"), 0, false, exitExpiredIntermediate},
+		{"path length violation also fails verification", f("path-length-exceeded", "chain-unverified"), 0, false, exitChainUnverified},
+		{"missing root", f("missing-root", "chain-unverified"), 0, true, exitMissingRoot},
+		{"parse error wins", f("expired-intermediate"), 1, true, exitParseError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.findings, tt.parseErrors, tt.rootMissing); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeErrorFindings(t *testing.T) {
+	tests := []struct {
+		ruleID string
+		want   int
+	}{
+		{"crl-revoked", exitErrorFinding},
+		{"ocsp-revoked", exitErrorFinding},
+		{"crl-signature-invalid", exitErrorFinding},
+		{"min-rsa-2048", exitErrorFinding}, // error-level validator
+		{"leaf-max-398d", 0},               // warning-level validator
+		{"ocsp-unavailable", 0},
+		{"crl-expired", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ruleID, func(t *testing.T) {
+			if got := exitCode([]finding{{ruleID: tt.ruleID}}, 0, false); got != tt.want {
+				t.Errorf("exitCode(%s) = %d, want %d", tt.ruleID, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExitCodeRevokedIntermediate runs a real CRL through checkCRL: a
+// revoked intermediate in an otherwise complete chain must fail the run.
+func TestExitCodeRevokedIntermediate(t *testing.T) {
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	root, rootKey := newTestCA(t, "Test Root", nil, nil, 1)
+	inter, _ := newTestCA(t, "Test Intermediate", root, rootKey, 2)
+
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                fixtureNow.Add(-time.Hour),
+		NextUpdate:                fixtureNow.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{SerialNumber: inter.SerialNumber, RevocationTime: fixtureNow.Add(-time.Minute)}},
+	}, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "root.crl")
+	if err := os.WriteFile(path, crl, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := checkCRL([]*x509.Certificate{inter, root}, path, fixtureNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exitCode(findings, 0, false); got != exitErrorFinding {
+		t.Errorf("exitCode() = %d for findings %+v, want %d", got, findings, exitErrorFinding)
+	}
+}
+
+// newTestCA issues a CA certificate from parent, or a self-signed root when
+// parent is nil.
+func newTestCA(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, serial int64) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	return newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             fixtureNow.Add(-time.Hour),
+		NotAfter:              fixtureNow.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, parent, parentKey)
+}
+
+// newTestCert signs template with a fresh P-256 key, self-signed when
+// parent is nil.
+func newTestCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// ocspFixture is a root, an intermediate and a leaf issued by it, with
+// OCSP responder certificates the intermediate delegated to with and
+// without the OCSPSigning EKU, and an unrelated CA to sign with instead.
+type ocspFixture struct {
+	root, inter, leaf     *x509.Certificate
+	interKey              *ecdsa.PrivateKey
+	delegate, noEKU       *x509.Certificate
+	delegateKey, noEKUKey *ecdsa.PrivateKey
+	otherCA               *x509.Certificate
+	otherKey              *ecdsa.PrivateKey
+}
+
+func newOCSPFixture(t *testing.T, responder string) *ocspFixture {
+	t.Helper()
+	f := &ocspFixture{}
+	var rootKey *ecdsa.PrivateKey
+	f.root, rootKey = newTestCA(t, "Test Root", nil, nil, 1)
+	f.inter, f.interKey = newTestCA(t, "Test Intermediate", f.root, rootKey, 2)
+	f.otherCA, f.otherKey = newTestCA(t, "Other CA", nil, nil, 3)
+	leaf := func(cn string, serial int64, ekus ...x509.ExtKeyUsage) *x509.Certificate {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    fixtureNow.Add(-time.Hour),
+			NotAfter:     fixtureNow.Add(24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  ekus,
+		}
+		if responder != "" {
+			template.OCSPServer = []string{responder}
+		}
+		cert, _ := newTestCert(t, template, f.inter, f.interKey)
+		return cert
+	}
+	f.leaf = leaf("leaf.example.com", 10, x509.ExtKeyUsageServerAuth)
+	f.delegate, f.delegateKey = newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(11),
+		Subject:      pkix.Name{CommonName: "Test OCSP Responder"},
+		NotBefore:    fixtureNow.Add(-time.Hour),
+		NotAfter:     fixtureNow.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, f.inter, f.interKey)
+	f.noEKU, f.noEKUKey = newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(12),
+		Subject:      pkix.Name{CommonName: "Test Server Not Responder"},
+		NotBefore:    fixtureNow.Add(-time.Hour),
+		NotAfter:     fixtureNow.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, f.inter, f.interKey)
+	return f
+}
+
+// response has signer sign an OCSP response for serial, embedding the
+// signer's certificate when embed is set.
+func (f *ocspFixture) response(t *testing.T, status int, serial *big.Int, signer *x509.Certificate, key *ecdsa.PrivateKey, embed bool) []byte {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: serial,
+		ThisUpdate:   fixtureNow.Add(-time.Hour),
+		NextUpdate:   fixtureNow.Add(time.Hour),
+	}
+	if embed {
+		template.Certificate = signer
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = fixtureNow.Add(-30 * time.Minute)
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+	der, err := ocsp.CreateResponse(f.inter, signer, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestParseOCSPResponse(t *testing.T) {
+	f := newOCSPFixture(t, "")
+	tests := []struct {
+		name       string
+		der        []byte
+		wantStatus int
+		wantErr    string
+	}{
+		{"good", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.inter, f.interKey, false), ocsp.Good, ""},
+		{"revoked", f.response(t, ocsp.Revoked, f.leaf.SerialNumber, f.inter, f.interKey, false), ocsp.Revoked, ""},
+		{"unknown", f.response(t, ocsp.Unknown, f.leaf.SerialNumber, f.inter, f.interKey, false), ocsp.Unknown, ""},
+		{"issuer embedded", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.inter, f.interKey, true), ocsp.Good, ""},
+		{"delegated responder", f.response(t, ocsp.Revoked, f.leaf.SerialNumber, f.delegate, f.delegateKey, true), ocsp.Revoked, ""},
+		{"malformed", []byte("not an OCSP response"), 0, "asn1"},
+		{"truncated", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.inter, f.interKey, false)[:40], 0, "asn1"},
+		{"responder refused", ocsp.UnauthorizedErrorResponse, 0, "unauthorized"},
+		{"wrong signer", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.otherCA, f.otherKey, false), 0, "signature does not verify"},
+		{"delegate without EKU", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.noEKU, f.noEKUKey, true), 0, "lacks the OCSPSigning EKU"},
+		{"delegate from another CA", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.otherCA, f.otherKey, true), 0, "was not issued by"},
+		{"other serial", f.response(t, ocsp.Good, big.NewInt(99), f.inter, f.interKey, false), 0, "no response matching"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseOCSPResponse(tt.der, f.leaf, f.inter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseOCSPResponse() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOCSPResponse() error = %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", resp.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == ocsp.Revoked && !resp.RevokedAt.Equal(fixtureNow.Add(-30*time.Minute)) {
+				t.Errorf("RevokedAt = %s, want %s", resp.RevokedAt, fixtureNow.Add(-30*time.Minute))
+			}
+		})
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	var der []byte
+	var gotContentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := ocsp.ParseRequest(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(der)
+	}))
+	defer srv.Close()
+	f := newOCSPFixture(t, srv.URL)
+
+	tests := []struct {
+		name string
+		der  []byte
+		want []string
+	}{
+		{"good", f.response(t, ocsp.Good, f.leaf.SerialNumber, f.inter, f.interKey, false), nil},
+		{"revoked", f.response(t, ocsp.Revoked, f.leaf.SerialNumber, f.inter, f.interKey, false), []string{"ocsp-revoked"}},
+		{"unknown", f.response(t, ocsp.Unknown, f.leaf.SerialNumber, f.inter, f.interKey, false), []string{"ocsp-unknown"}},
+		{"malformed", []byte{0x30, 0x03, 0x0a, 0x01}, []string{"ocsp-unavailable"}},
+		{"wrong signer", f.response(t, ocsp.Revoked, f.leaf.SerialNumber, f.otherCA, f.otherKey, false), []string{"ocsp-unavailable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der = tt.der
+			var got []string
+			for _, fd := range checkRevocation([]*x509.Certificate{f.leaf, f.inter, f.root}) {
+				got = append(got, fd.ruleID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkRevocation() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if gotContentType != "application/ocsp-request" {
+		t.Errorf("Content-Type = %q, want application/ocsp-request", gotContentType)
+	}
+
+	// A responder that cannot be reached says nothing about the cert.
+	srv.Close()
+	findings := checkRevocation([]*x509.Certificate{f.leaf, f.inter, f.root})
+	if len(findings) != 1 || findings[0].ruleID != "ocsp-unavailable" {
+		t.Errorf("checkRevocation() with the responder down = %+v, want one ocsp-unavailable finding", findings)
+	}
+}
+
+// TestConnectOmittedRoot serves a leaf and intermediate without their root,
+// as healthy servers do: the chain must verify, and the expected root count
+// as present, once the root is in the trust store -connect passes along.
+func TestConnectOmittedRoot(t *testing.T) {
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	root, rootKey := newTestCA(t, "Test Root", nil, nil, 1)
+	inter, interKey := newTestCA(t, "Test Intermediate", root, rootKey, 2)
+	leaf, leafKey := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    fixtureNow.Add(-time.Hour),
+		NotAfter:     fixtureNow.Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, inter, interKey)
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.Raw, inter.Raw}, PrivateKey: leafKey}}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	data, hostFindings, err := fetchServerChain(srv.Listener.Addr().String(), "localhost", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostFindings) != 0 {
+		t.Errorf("fetchServerChain() findings for a matching name = %+v, want none", hostFindings)
+	}
+	_, hostFindings, err = fetchServerChain(srv.Listener.Addr().String(), "other.example.com", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostFindings) != 1 || hostFindings[0].ruleID != "hostname-mismatch" {
+		t.Errorf("fetchServerChain() findings for another name = %+v, want one hostname-mismatch", hostFindings)
+	}
+	certs, err := parseCerts(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("server presented %d certificates, want 2", len(certs))
+	}
+
+	rootFile := filepath.Join(t.TempDir(), "root.pem")
+	if err := os.WriteFile(rootFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	trustStore, err := connectTrustStore(rootFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("without the root", func(t *testing.T) {
+		if _, missing := checkExpectedRoots(certs, []string{"Test Root"}, nil); !missing {
+			t.Error("checkExpectedRoots() reported the root present with no trust store")
+		}
+		if findings, _ := checkChainVerification(certs, fixtureNow, nil); len(findings) != 1 || findings[0].ruleID != "chain-unverified" {
+			t.Errorf("checkChainVerification() findings = %+v, want one chain-unverified", findings)
+		}
+	})
+
+	t.Run("root in the trust store", func(t *testing.T) {
+		if findings, missing := checkExpectedRoots(certs, []string{"Test Root"}, trustStore); missing || len(findings) != 0 {
+			t.Errorf("checkExpectedRoots() = %+v, %v; want no findings", findings, missing)
+		}
+		findings, chains := checkChainVerification(certs, fixtureNow, trustStore)
+		if len(findings) != 0 {
+			t.Errorf("checkChainVerification() findings = %+v, want none", findings)
+		}
+		if len(chains) != 1 || len(chains[0]) != 3 || !chains[0][2].Equal(root) {
+			t.Errorf("checkChainVerification() chains = %v, want leaf → intermediate → Test Root", chains)
+		}
+		if got := exitCode(findings, 0, false); got != 0 {
+			t.Errorf("exitCode() = %d, want 0", got)
+		}
+	})
+}
+
+func TestConnectAddress(t *testing.T) {
+	tests := []struct {
+		target, addr, host string
+		wantErr            bool
+	}{
+		{target: "example.com", addr: "example.com:443", host: "example.com"},
+		{target: "example.com:8443", addr: "example.com:8443", host: "example.com"},
+		{target: "192.0.2.1", addr: "192.0.2.1:443", host: "192.0.2.1"},
+		{target: "[::1]:8443", addr: "[::1]:8443", host: "::1"},
+		{target: "[::1]", addr: "[::1]:443", host: "::1"},
+		{target: "::1", addr: "[::1]:443", host: "::1"},
+		{target: "example.com:https", wantErr: true},
+		{target: "example.com:0", wantErr: true},
+		{target: "example.com:65536", wantErr: true},
+		{target: ":443", wantErr: true},
+		{target: "", wantErr: true},
+		{target: "a:b:c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			addr, host, err := connectAddress(tt.target)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("connectAddress(%q) = %q, %q; want an error", tt.target, addr, host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("connectAddress(%q) error = %v", tt.target, err)
+			}
+			if addr != tt.addr || host != tt.host {
+				t.Errorf("connectAddress(%q) = %q, %q; want %q, %q", tt.target, addr, host, tt.addr, tt.host)
+			}
+		})
+	}
+}
+
+// fixtureFindings runs the structural checks over a testdata bundle, as
+// main does, at fixtureNow.
+func fixtureFindings(t *testing.T, certs []*x509.Certificate) []finding {
+	t.Helper()
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	findings, _ := checkExpectedRoots(certs, []string{"ISRG Root X1"}, nil)
+	chainFindings, _ := checkChainVerification(certs, fixtureNow, nil)
+	findings = append(findings, chainFindings...)
+	findings = append(findings, checkIssuersPresent(certs)...)
+	return append(findings, checkRootPurposes(certs)...)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	type result struct{ ruleID, level, location string }
+	tests := []struct {
+		bundle string
+		want   []result
+	}{
+		{"complete", nil},
+		{"duplicate-root", nil},
+		{"no-letsencrypt", nil},
+		{"expired-intermediate", []result{{"chain-unverified", "error", "certificate[1]"}}},
+		{"intermediate-without-root", []result{
+			{"missing-root", "error", "certificate[1]"},
+			{"chain-unverified", "error", "certificate[1]"},
+			{"missing-issuer", "warning", "certificate[1]"},
+		}},
+		{"key-id-mismatch", []result{
+			{"missing-root", "error", "certificate[1]"},
+			{"chain-unverified", "error", "certificate[1]"},
+			{"missing-issuer", "warning", "certificate[1]"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bundle, func(t *testing.T) {
+			findings := fixtureFindings(t, loadFixture(t, tt.bundle))
+			var buf bytes.Buffer
+			if err := writeSARIF(&buf, tt.bundle+".pem", findings, nil, map[string]interface{}{"trustScore": 75}); err != nil {
+				t.Fatal(err)
+			}
+
+			var log struct {
+				Version string `json:"version"`
+				Runs    []struct {
+					Tool struct {
+						Driver struct {
+							Rules []struct {
+								ID                   string `json:"id"`
+								DefaultConfiguration struct {
+									Level string `json:"level"`
+								} `json:"defaultConfiguration"`
+							} `json:"rules"`
+						} `json:"driver"`
+					} `json:"tool"`
+					Properties map[string]interface{} `json:"properties"`
+					Results    []struct {
+						RuleID    string `json:"ruleId"`
+						Level     string `json:"level"`
+						Locations []struct {
+							PhysicalLocation struct {
+								ArtifactLocation struct {
+									URI string `json:"uri"`
+								} `json:"artifactLocation"`
+							} `json:"physicalLocation"`
+							LogicalLocations []struct {
+								Name string `json:"name"`
+							} `json:"logicalLocations"`
+						} `json:"locations"`
+					} `json:"results"`
+				} `json:"runs"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+				t.Fatalf("SARIF is not JSON: %v", err)
+			}
+			if log.Version != "2.1.0" || len(log.Runs) != 1 {
+				t.Fatalf("version %q with %d runs, want 2.1.0 with 1", log.Version, len(log.Runs))
+			}
+			run := log.Runs[0]
+			if run.Properties["trustScore"] != 75.0 {
+				t.Errorf("properties = %v, want trustScore 75", run.Properties)
+			}
+
+			rules := map[string]string{}
+			for _, r := range run.Tool.Driver.Rules {
+				rules[r.ID] = r.DefaultConfiguration.Level
+			}
+			for _, r := range sarifRules {
+				if rules[r.id] != r.level {
+					t.Errorf("rule %s has level %q, want %q", r.id, rules[r.id], r.level)
+				}
+			}
+
+			var got []result
+			for _, r := range run.Results {
+				if len(r.Locations) != 1 || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != tt.bundle+".pem" {
+					t.Errorf("%s: locations %+v, want one in %s.pem", r.RuleID, r.Locations, tt.bundle)
+					continue
+				}
+				location := ""
+				if l := r.Locations[0].LogicalLocations; len(l) > 0 {
+					location = l[0].Name
+				}
+				got = append(got, result{r.RuleID, r.Level, location})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingLevel(t *testing.T) {
+	tests := map[string]string{
+		"missing-root":         "error",
+		"missing-issuer":       "warning",
+		"not-publicly-trusted": "note",
+		"min-rsa-2048":         "error",   // validator
+		"leaf-max-398d":        "warning", // validator
+		"no-such-rule":         "",
+	}
+	for ruleID, want := range tests {
+		if got := findingLevel(ruleID); got != want {
+			t.Errorf("findingLevel(%s) = %q, want %q", ruleID, got, want)
+		}
+	}
+}
+
+func TestLookupValidators(t *testing.T) {
+	builtin := len(sarifRules)
+	enabled, err := lookupValidators("min-rsa-2048, ca-has-ski,min-rsa-2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, rv := range enabled {
+		names = append(names, rv.name)
+	}
+	if want := []string{"min-rsa-2048", "ca-has-ski"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("enabled = %v, want %v", names, want)
+	}
+	if len(sarifRules) != builtin {
+		t.Errorf("lookupValidators grew sarifRules from %d to %d", builtin, len(sarifRules))
+	}
+	if _, err := lookupValidators("no-such-validator"); err == nil {
+		t.Error("unknown validator: want error")
+	}
+
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, "bundle.pem", nil, enabled, nil); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	count := map[string]int{}
+	for _, r := range log.Runs[0].Tool.Driver.Rules {
+		count[r.ID]++
+	}
+	if count["min-rsa-2048"] != 1 || count["ca-has-ski"] != 1 || count["leaf-max-398d"] != 0 {
+		t.Errorf("validator rules = %v, want min-rsa-2048 and ca-has-ski once each", count)
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	bundles := []string{"complete", "intermediate-without-root", "no-letsencrypt", "duplicate-root", "expired-intermediate", "key-id-mismatch"}
+	for _, bundle := range bundles {
+		t.Run(bundle, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeTree(&buf, buildChainGraph(loadFixture(t, bundle)))
+
+			golden := filepath.Join("testdata", bundle+".tree.golden")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("output differs from %s:\n--- got ---\n%s--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+// TestWriteTreeIssuerLoop builds two CAs that issued each other, with a
+// leaf under one of them: no root anchors them, so they are orphans, and
+// the tree must print each once rather than recurse forever.
+func TestWriteTreeIssuerLoop(t *testing.T) {
+	keyA, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := func(cn string, serial int64, keyID byte) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: cn},
+			SubjectKeyId:          []byte{keyID},
+			NotBefore:             fixtureNow.Add(-time.Hour),
+			NotAfter:              fixtureNow.Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+	}
+	issue := func(template, parent *x509.Certificate, pub *ecdsa.PublicKey, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	tmplA, tmplB := template("Loop CA A", 1, 0xA), template("Loop CA B", 2, 0xB)
+	caA := issue(tmplA, tmplB, &keyA.PublicKey, keyB)
+	caB := issue(tmplB, tmplA, &keyB.PublicKey, keyA)
+	leaf, _ := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "loop.example.com"},
+		NotBefore:    fixtureNow.Add(-time.Hour),
+		NotAfter:     fixtureNow.Add(time.Hour),
+	}, caA, keyA)
+
+	g := buildChainGraph([]*x509.Certificate{caA, caB, leaf})
+	if !isIssuerLoop(g, 0) || !isIssuerLoop(g, 1) || isIssuerLoop(g, 2) {
+		t.Fatalf("isIssuerLoop() = %v, %v, %v; want true, true, false", isIssuerLoop(g, 0), isIssuerLoop(g, 1), isIssuerLoop(g, 2))
+	}
+
+	var buf bytes.Buffer
+	writeTree(&buf, g)
+	want := `=== Roots ===
+(none)
+
+=== Orphans ===
+Loop CA A (#1, intermediate), in an issuer loop with no root
+├── Loop CA B (#2, intermediate)
+└── loop.example.com (#3, leaf)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeTree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTrustScore(t *testing.T) {
+	saved := out
+	out = io.Discard
+	defer func() { out = saved }()
+
+	// Weights: complete chains 30, unexpired 20, strong signatures 15, no
+	// duplicates 10, trusted roots 25. The fixture roots are test-generated,
+	// so they only count as trusted when listed as internal roots.
+	tests := []struct {
+		bundle         string
+		extra          []finding
+		public, intern int
+	}{
+		{"complete", nil, 75, 100},
+		{"complete", []finding{{ruleID: "weak-signature", certIndex: 1}}, 68, 93},
+		{"duplicate-root", nil, 72, 97},
+		{"expired-intermediate", nil, 65, 90},
+		{"intermediate-without-root", nil, 45, 45},
+		{"key-id-mismatch", nil, 60, 85},
+		{"no-letsencrypt", nil, 75, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bundle, func(t *testing.T) {
+			certs := loadFixture(t, tt.bundle)
+			findings := append(fixtureFindings(t, certs), tt.extra...)
+			internal := append([]publicRoot{}, publicRootSnapshot...)
+			for i, cert := range certs {
+				// expired-cert findings come from main's per-certificate pass
+				if fixtureNow.After(cert.NotAfter) {
+					findings = append(findings, finding{ruleID: "expired-cert", certIndex: i + 1})
+				}
+				if isSelfSigned(cert) {
+					sum := sha256.Sum256(cert.Raw)
+					internal = append(internal, publicRoot{fingerprint: strings.ToUpper(hex.EncodeToString(sum[:]))})
+				}
+			}
+
+			g := buildChainGraph(certs)
+			if got := trustScore(g, findings, publicRootSnapshot); got != tt.public {
+				t.Errorf("trustScore() with the public snapshot = %d, want %d", got, tt.public)
+			}
+			if got := trustScore(g, findings, internal); got != tt.intern {
+				t.Errorf("trustScore() with the roots as internal = %d, want %d", got, tt.intern)
+			}
+		})
+	}
+
+	if got := trustScore(buildChainGraph(nil), nil, publicRootSnapshot); got != 0 {
+		t.Errorf("trustScore() of an empty bundle = %d, want 0", got)
+	}
+}
+
+// TestCheckCrossSigns recreates Let's Encrypt's legacy chain: ISRG Root X1
+// cross-signed by DST Root CA X3, which expired on 2021-09-30, three years
+// before the cross-sign itself.
+func TestCheckCrossSigns(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	isrg := pkix.Name{CommonName: "ISRG Root X1", Organization: []string{"Internet Security Research Group"}, Country: []string{"US"}}
+	ca := func(subject pkix.Name, serial int64, notBefore, notAfter string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               subject,
+			NotBefore:             date(notBefore),
+			NotAfter:              date(notAfter),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+	}
+	dst, dstKey := newTestCert(t, ca(pkix.Name{CommonName: "DST Root CA X3", Organization: []string{"Digital Signature Trust Co."}}, 1, "2000-09-30", "2021-09-30"), nil, nil)
+	crossSigned, _ := newTestCert(t, ca(isrg, 2, "2021-01-20", "2024-09-30"), dst, dstKey)
+	standalone, _ := newTestCert(t, ca(isrg, 3, "2015-06-04", "2035-06-04"), nil, nil)
+
+	tests := []struct {
+		name    string
+		certs   []*x509.Certificate
+		now     string
+		finding bool
+		report  string
+	}{
+		{"cross-sign only, before DST expiry", []*x509.Certificate{crossSigned, dst}, "2021-06-01", true,
+			"⚠️  Standalone root is missing; clients will need it after 2021-09-30"},
+		{"cross-sign only, after DST expiry", []*x509.Certificate{crossSigned, dst}, "2025-06-01", true,
+			"❌ Cross-sign has already reached end of life and the standalone root is missing"},
+		{"issuer not in bundle", []*x509.Certificate{crossSigned}, "2025-06-01", true,
+			"End of life:     2024-09-30"},
+		{"standalone root present", []*x509.Certificate{crossSigned, dst, standalone}, "2025-06-01", false,
+			"✅ Standalone root is also in the bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			saved := out
+			out = &buf
+			defer func() { out = saved }()
+
+			findings := checkCrossSigns(tt.certs, date(tt.now))
+			if got := len(findings) == 1 && findings[0].ruleID == "cross-sign-eol" && findings[0].certIndex == 1; got != tt.finding {
+				t.Errorf("findings = %+v, want cross-sign-eol on certificate 1: %v", findings, tt.finding)
+			}
+			report := buf.String()
+			if !strings.Contains(report, tt.report) {
+				t.Errorf("report does not contain %q:\n%s", tt.report, report)
+			}
+			if !strings.Contains(report, "DST Root CA X3 expired 2021-09-30") {
+				t.Errorf("report lacks the known cross-sign note:\n%s", report)
+			}
+		})
+	}
+
+	for _, bundle := range []string{"complete", "intermediate-without-root", "no-letsencrypt", "duplicate-root", "expired-intermediate", "key-id-mismatch"} {
+		saved := out
+		out = io.Discard
+		if findings := checkCrossSigns(loadFixture(t, bundle), fixtureNow); len(findings) != 0 {
+			t.Errorf("%s: findings = %+v, want none", bundle, findings)
+		}
+		out = saved
+	}
+}
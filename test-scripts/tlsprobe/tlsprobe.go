@@ -0,0 +1,126 @@
+// Package tlsprobe makes an HTTPS request to an endpoint with a given trust
+// pool and reports what the TLS connection looked like. It does not print
+// anything, so it can be embedded in other programs, such as an operator's
+// reconcile loop, as well as driving the test_tls_connect CLI.
+package tlsprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// ProbeResult describes a successful probe.
+type ProbeResult struct {
+	// StatusCode is the HTTP status of the response. Any status counts
+	// as success; the probe is about TLS, not the endpoint's semantics.
+	StatusCode int
+
+	// TLSVersion is the negotiated protocol version, e.g. tls.VersionTLS13.
+	TLSVersion uint16
+
+	// CipherSuite is the negotiated cipher suite, e.g.
+	// tls.TLS_AES_128_GCM_SHA256.
+	CipherSuite uint16
+
+	// PeerCertificates is the chain the server presented, leaf first.
+	PeerCertificates []*x509.Certificate
+
+	// Handshake is how long the TLS handshake took.
+	Handshake time.Duration
+}
+
+// TLSVersionName returns the negotiated version as a string, e.g. "TLS 1.3".
+func (r *ProbeResult) TLSVersionName() string {
+	return tls.VersionName(r.TLSVersion)
+}
+
+// CipherSuiteName returns the negotiated cipher suite as a string, e.g.
+// "TLS_AES_128_GCM_SHA256".
+func (r *ProbeResult) CipherSuiteName() string {
+	return tls.CipherSuiteName(r.CipherSuite)
+}
+
+// Prober probes endpoints with optional connection customization. The zero
+// value uses a plain http.Transport.
+type Prober struct {
+	// Transport, if set, builds the transport for each probe from its TLS
+	// config. Use it to apply options such as disabled keep-alives or a
+	// custom ClientHello; it may modify the config it is given.
+	Transport func(*tls.Config) *http.Transport
+}
+
+// ProbeWithCAPool GETs url trusting only the roots in pool and returns the
+// outcome. An error means the request failed, typically because the
+// server's certificate did not verify against pool.
+func ProbeWithCAPool(url string, pool *x509.CertPool, timeout time.Duration) (*ProbeResult, error) {
+	return (&Prober{}).ProbeWithCAPool(url, pool, timeout)
+}
+
+// ProbeWithCAPoolContext is like ProbeWithCAPool but is bounded by ctx
+// instead of a timeout, so a caller such as a reconcile loop can cancel it.
+func ProbeWithCAPoolContext(ctx context.Context, url string, pool *x509.CertPool) (*ProbeResult, error) {
+	return (&Prober{}).ProbeWithCAPoolContext(ctx, url, pool)
+}
+
+// ProbeWithCAPool is like the package-level ProbeWithCAPool but builds its
+// transport with p.Transport.
+func (p *Prober) ProbeWithCAPool(url string, pool *x509.CertPool, timeout time.Duration) (*ProbeResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return p.ProbeWithCAPoolContext(ctx, url, pool)
+}
+
+// ProbeWithCAPoolContext is like the package-level ProbeWithCAPoolContext
+// but builds its transport with p.Transport.
+func (p *Prober) ProbeWithCAPoolContext(ctx context.Context, url string, pool *x509.CertPool) (*ProbeResult, error) {
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	var transport *http.Transport
+	if p.Transport != nil {
+		transport = p.Transport(tlsConfig)
+	} else {
+		transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{Transport: transport}
+
+	var start time.Time
+	var handshake time.Duration
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() { start = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			handshake = time.Since(start)
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	result := &ProbeResult{
+		StatusCode: resp.StatusCode,
+		Handshake:  handshake,
+	}
+	if resp.TLS != nil {
+		result.TLSVersion = resp.TLS.Version
+		result.CipherSuite = resp.TLS.CipherSuite
+		result.PeerCertificates = resp.TLS.PeerCertificates
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package tlsprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return server, pool
+}
+
+func TestProbeWithCAPool(t *testing.T) {
+	server, pool := newTestServer(t)
+
+	result, err := ProbeWithCAPool(server.URL, pool, 5*time.Second)
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if result.StatusCode != http.StatusTeapot {
+		t.Errorf("StatusCode = %d, want %d", result.StatusCode, http.StatusTeapot)
+	}
+	if result.TLSVersion < tls.VersionTLS12 {
+		t.Errorf("TLSVersion = %s, want at least TLS 1.2", result.TLSVersionName())
+	}
+	if result.CipherSuite == 0 {
+		t.Errorf("CipherSuite was not recorded")
+	}
+	if len(result.PeerCertificates) == 0 || !result.PeerCertificates[0].Equal(server.Certificate()) {
+		t.Errorf("PeerCertificates does not start with the server's certificate")
+	}
+}
+
+func TestProbeWithCAPoolUntrusted(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	result, err := ProbeWithCAPool(server.URL, x509.NewCertPool(), 5*time.Second)
+	if err == nil {
+		t.Fatalf("expected a verification error, got HTTP %d", result.StatusCode)
+	}
+}
+
+func TestProberTransport(t *testing.T) {
+	server, pool := newTestServer(t)
+
+	called := false
+	p := &Prober{Transport: func(cfg *tls.Config) *http.Transport {
+		called = true
+		cfg.MaxVersion = tls.VersionTLS12
+		return &http.Transport{TLSClientConfig: cfg}
+	}}
+	result, err := p.ProbeWithCAPool(server.URL, pool, 5*time.Second)
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if !called {
+		t.Errorf("Prober.Transport was not used")
+	}
+	if result.TLSVersion != tls.VersionTLS12 {
+		t.Errorf("TLSVersion = %s, want TLS 1.2 from the custom transport", result.TLSVersionName())
+	}
+}
+
+func TestProbeWithCAPoolContextCanceled(t *testing.T) {
+	server, pool := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ProbeWithCAPoolContext(ctx, server.URL, pool); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}